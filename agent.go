@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/eolymp/go-agent/tracing"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -16,21 +20,57 @@ type Agent struct {
 	tools       Toolset                                // toolset for the agent
 	memory      Memory                                 // memory provides a backend for storing conversation history between turns
 	messages    []Message                              // list of starter messages are added before the messages from memory, this is normally a system message
+	ephemeral   []Message                              // messages added after the messages from memory, they are not stored in memory
+	sysPrefix   []string                               // text prepended to the system message
+	sysSuffix   []string                               // text appended to the system message
 	values      map[string]any                         // values for template substitution in messages
+	defaults    map[string]any                         // default values for template substitution, overridden by values
+	noReserved  bool                                   // do not add reserved values (date, time, datetime) for template substitution
 	model       string                                 // model to be used for completion
 	models      map[string]string                      // deprecated, to be moved to completer, additional mapping for model name (probably should be in completer :thinking:...)
+	fallbacks   map[string][]string                    // models used when the model is overloaded or unavailable, in order of preference
 	temperature *float32                               // temperature parameter for completion
 	maxTokens   *int64                                 // max tokens parameter for completion
 	topP        *float32                               // top_p parameter for completion
 	topK        *int32                                 // top_k parameter for completion
+	seed        *int64                                 // seed parameter for completion
 	useCache    *bool                                  // use prompt caching (Anthropic specific)
+	cachePrefix int                                    // number of leading messages marked as cacheable, see WithCachedPrefix
+	maxCost     float64                                // max cost of the run in USD, 0 - no limit
+	pricing     Pricing                                // model prices used to calculate cost of the run
 	iterations  int                                    // max number of iterations for agentic loop
+	continues   int                                    // max number of times the model is asked to continue a reply cut off by max tokens limit
+	loopLimit   int                                    // number of identical tool calls in a row which is considered a stuck loop, 0 - no detection
+	loopAction  LoopAction                             // action taken when a stuck loop is detected
 	parallelism int                                    // number of tool calls executed in parallel, 1 - sequential run, -1 - no limit on parallelism
+	parallel    *bool                                  // allow the model to request several tool calls at once, if nil it's derived from parallelism
+	semaphore   chan struct{}                          // limits the number of concurrent completions of this agent and agents it runs, see WithMaxConcurrentAgents
 	betas       []string                               // additional flags to enable beta features
 	container   *Container                             // container to be used for LLM (only available in Anthropic models)
 	reasoning   *Reasoning                             // reasoning configuration (only supported by Anthropic models)
+	format      *ResponseFormat                        // format of the final reply, see WithJSONSchema
+	retries     map[string]ToolRetry                   // retry policies for tools by name
+	toolValues  map[any]any                            // values added to the context of tool calls
+	extra       map[string]any                         // provider specific parameters of completion request
+	logProbs    bool                                   // request log probabilities of output tokens
+	topLogProbs int                                    // number of alternatives returned with log probabilities of each token
+	errFormat   func(name string, err error) string    // formats tool errors presented to the model
+	transforms  []ToolResultTransformer                // transform tool results before they are added to memory
+	maxResult   int                                    // max size of tool result in bytes, larger results are truncated, 0 - no limit
+	toolTimeout time.Duration                          // max duration of a single tool call, 0 - no limit
+	enabled     map[string]bool                        // if set, only these tools are available to the model
+	disabled    map[string]bool                        // tools which are not available to the model
+	filters     []func(context.Context, Tool) bool     // predicates deciding if the tool is available in the run, see WithToolFilter
 	dynamics    []OptionLoader                         // lazy loaded options are loaded just before executing agentic loop to define dynamic parameters (load from an external backend)
 	approver    []func(call ToolCall) ToolCallApproval // approvers automatically approve tool calls
+	approvals   map[string]bool                        // tool calls approved explicitly by their ID
+	protected   map[string]bool                        // tool tags which require explicit approval of tool calls
+	preprocess  []func(messages []Message) []Message   // preprocessors transform messages right before they are sent to the model, memory is not affected
+	traceTags   []string                               // tags attached to all spans of the run
+	traceProj   string                                 // tracing project of the run, if empty the project of the tracer is used
+	runID       string                                 // ID of the run used for correlation, generated if empty
+	observers   []Observer                             // observers are notified after every iteration of agentic loop
+	stops       []StopCondition                        // conditions evaluated after tool calls, the run ends when any of them is met
 	finalizer   []func(reply *AssistantMessage) error  // finalizers run with final message to ensure it matches expected value, if finalizer returns error, it's added as user message and an additional turn is executed automatically
 }
 
@@ -65,43 +105,118 @@ func (a Agent) Memory() Memory {
 	return a.memory
 }
 
-// Ask is deprecated, use Run instead.
+// Ask runs the agent and discards the reply.
+//
+// Deprecated: use Run instead.
 func (a Agent) Ask(ctx context.Context, opts ...Option) (err error) {
 	_, err = a.Run(ctx, opts...)
 	return err
 }
 
+// Run executes the agentic loop and returns the final reply of the model. Options are applied to a copy of the
+// agent for this run only.
+//
+// Run is safe for concurrent use: tools, values and messages added by options are isolated per run. Memory is shared
+// by all runs of the agent, so a run fails with ErrMemoryInUse while another run uses the same memory. Give every
+// concurrent run its own memory with WithMemory (e.g. WithMemory(NewStaticMemory())).
+//
+// The request must contain at least one message (e.g. a system prompt, a user message added with WithUserMessage or a
+// conversation stored in memory), otherwise Run fails with ErrNoMessages.
 func (a Agent) Run(ctx context.Context, opts ...Option) (reply AssistantMessage, err error) {
 	c := a.clone()
 	for _, opt := range opts {
 		opt(&c)
 	}
 
+	// nested runs (e.g. specialists) inherit ID of the calling run, so they are correlated with it
+	if c.runID == "" {
+		c.runID, _ = RunIDFromContext(ctx)
+	}
+
+	if c.runID == "" {
+		c.runID = uuid.NewString()
+	}
+
+	ctx = withRunID(ctx, c.runID)
+	ctx = tracing.WithTags(ctx, c.traceTags...)
+	ctx = tracing.WithProject(ctx, c.traceProj)
+
 	span, ctx := tracing.StartSpan(ctx, fmt.Sprintf("agent %q", c.name), tracing.Kind(tracing.SpanTask))
 	defer span.CloseWithError(err)
 
+	span.SetMetadata("run_id", c.runID)
+
 	for _, d := range c.dynamics {
 		if err := d(ctx, &c); err != nil {
 			return reply, fmt.Errorf("failed to load options: %w", err)
 		}
 	}
 
-	var tools = c.tools.List()
-	var model = c.model
+	release, ok := claim(c.memory)
+	if !ok {
+		return reply, ErrMemoryInUse
+	}
+
+	defer release()
+
+	ctx = withMemory(ctx, c.memory)
+	ctx = withSemaphore(ctx, c.semaphore)
+
+	var tools []Tool
+	var model = c.resolve()
+
+	if limit, ok := ModelMaxTokens(model); ok && c.maxTokens != nil && *c.maxTokens > limit {
+		slog.WarnContext(ctx, "Max tokens exceed the limit of the model, the limit is used instead", "channel", "llm", "model", model, "max_tokens", *c.maxTokens, "limit", limit)
+		c.maxTokens = &limit
+	}
+
+	if r := c.reasoning; r != nil && r.Enabled {
+		if r.Budget > 0 && r.Budget < minReasoningBudget {
+			return reply, fmt.Errorf("reasoning budget (%d tokens) must be at least %d tokens", r.Budget, minReasoningBudget)
+		}
+
+		if r.Budget > 0 && c.maxTokens != nil && int64(r.Budget) >= *c.maxTokens {
+			return reply, fmt.Errorf("reasoning budget (%d tokens) must be less than max tokens (%d)", r.Budget, *c.maxTokens)
+		}
+
+		switch r.Effort {
+		case "", "low", "medium", "high":
+		default:
+			return reply, fmt.Errorf("reasoning effort %q is not supported, use low, medium or high", r.Effort)
+		}
+	}
+
+	for _, tool := range c.tools.List() {
+		if c.available(ctx, tool) {
+			tools = append(tools, tool)
+		}
+	}
 
 	// Render starter messages with template values
-	system := make([]Message, len(c.messages))
-	for i, m := range c.messages {
-		system[i] = render(m, c.values)
+	starter := c.starter()
+	values := templateValues(!c.noReserved, c.defaults, c.values)
+	system := make([]Message, len(starter))
+	for i, m := range starter {
+		system[i] = render(m, values)
 	}
 
 	// run tool calls, if previous loop ended with unapproved tool calls
 	if last, ok := LastMessageAsAssistant(c.memory); ok {
-		if err := c.call(ctx, last); err != nil {
+		if _, err := c.call(ctx, last); err != nil {
 			return last, err
 		}
 	}
 
+	// content of replies truncated by max tokens limit, it's stitched together with the final reply
+	var partial []MessageBlock
+	var continuations int
+
+	// detects the model repeating the same tool call
+	loops := loopDetector{limit: c.loopLimit}
+
+	// cost of the run so far and of the last completion, which is used to estimate cost of the next one
+	var cost, last float64
+
 loop:
 	for i := 0; i < c.iterations; i++ {
 		var messages []Message
@@ -111,42 +226,155 @@ loop:
 			messages = append(messages, message)
 		}
 
-		resp, err := c.complete(ctx, CompletionRequest{
+		messages = append(messages, c.ephemeral...)
+
+		for _, p := range c.preprocess {
+			messages = p(messages)
+		}
+
+		messages = normalize(messages)
+
+		if len(messages) == 0 {
+			return reply, ErrNoMessages
+		}
+
+		// next completion sends at least as many tokens as the previous one
+		if c.maxCost > 0 && cost+last > c.maxCost {
+			return reply, ErrBudgetExceeded
+		}
+
+		req := CompletionRequest{
 			Model:             model,
 			Messages:          messages,
 			Tools:             tools,
-			ParallelToolCalls: c.parallelism != 1 && c.parallelism != 0,
+			ParallelToolCalls: c.parallelToolCalls(),
 			ToolChoice:        ToolChoiceAuto,
 			Temperature:       c.temperature,
 			MaxTokens:         c.maxTokens,
 			TopP:              c.topP,
 			TopK:              c.topK,
+			Seed:              c.seed,
 			UseCache:          c.useCache,
+			CachedPrefix:      c.cachePrefix,
 			Container:         c.container,
 			Betas:             c.betas,
 			Reasoning:         c.reasoning,
-		})
+			ResponseFormat:    c.format,
+			IdempotencyKey:    uuid.NewString(),
+			Extra:             c.extra,
+			LogProbs:          c.logProbs,
+			TopLogProbs:       c.topLogProbs,
+		}
+
+		release, err := acquire(ctx)
+		if err != nil {
+			return reply, err
+		}
+
+		resp, err := c.complete(ctx, req)
+		release()
 
 		if err != nil {
 			return reply, err
 		}
 
+		// the model which actually answered may be a fallback model, it's priced first
+		if c.maxCost > 0 {
+			var ok bool
+			if last, ok = c.pricing.Cost(resp.Model, resp.Usage); !ok {
+				last, _ = c.pricing.Cost(model, resp.Usage)
+			}
+
+			cost += last
+		}
+
 		// convert completion response to assistant message
-		reply = AssistantMessage{Content: resp.Content}
+		reply = AssistantMessage{Content: resp.Content, RunID: c.runID}
+		event := IterationEvent{Iteration: i, Request: req, Response: resp}
+
+		// an empty reply is not written to memory, otherwise a retry would send a blank turn of the model
+		if resp.FinishReason != FinishReasonContentFilter && strings.TrimSpace(reply.Text()) == "" && len(event.Calls()) == 0 {
+			c.observe(ctx, event)
+
+			if len(partial) > 0 {
+				reply = AssistantMessage{Content: partial, RunID: c.runID}
+			}
+
+			return reply, ErrEmptyResponse
+		}
+
+		// tool calls of a truncated reply can not be continued: a continuation without their results is rejected by
+		// providers, and arguments of the last call may be cut off
+		truncated := resp.FinishReason == FinishReasonLength && continuations < c.continues
+		if truncated && len(event.Calls()) > 0 {
+			c.observe(ctx, event)
+			return reply, ErrTruncatedToolCalls
+		}
 
 		if err := c.memory.Append(ctx, reply); err != nil {
 			return reply, err
 		}
 
-		switch resp.FinishReason {
+		// ask model to continue if response was cut off
+		if truncated {
+			continuations++
+			partial = stitch(partial, reply.Content)
+
+			if err := c.memory.Append(ctx, NewUserMessage("Your response was cut off. Continue exactly where you stopped, do not repeat what you have already written.")); err != nil {
+				return reply, err
+			}
+
+			c.observe(ctx, event)
+			continue
+		}
+
+		if len(partial) > 0 {
+			reply = AssistantMessage{Content: stitch(partial, reply.Content), RunID: c.runID}
+			partial = nil
+		}
+
+		reason := resp.FinishReason
+
+		// some providers report tool calls with stop finish reason
+		if reason == FinishReasonStop && len(event.Calls()) > 0 {
+			reason = FinishReasonToolCalls
+		}
+
+		switch reason {
 		case FinishReasonToolCalls:
 			// call tools
-			if err := c.call(ctx, reply); err != nil {
+			results, err := c.call(ctx, reply)
+
+			event.Results = results
+			c.observe(ctx, event)
+
+			if err != nil {
+				return reply, err
+			}
+
+			if stop, err := c.stop(ctx, &reply); err != nil || stop {
 				return reply, err
 			}
 
+			if call, stuck := loops.add(event.Calls()); stuck {
+				if c.loopAction == LoopActionAbort {
+					return reply, ErrStuckLoop
+				}
+
+				if err := c.memory.Append(ctx, NewUserMessage(nudge(call, c.loopLimit))); err != nil {
+					return reply, err
+				}
+
+				loops.reset()
+			}
+
 			continue
+		case FinishReasonContentFilter:
+			c.observe(ctx, event)
+			return reply, ErrContentFiltered
 		default:
+			c.observe(ctx, event)
+
 			for _, f := range c.finalizer {
 				if err := f(&reply); err != nil {
 					if err := c.memory.Append(ctx, NewUserMessage("ERROR: "+err.Error())); err != nil {
@@ -164,23 +392,124 @@ loop:
 	return reply, nil
 }
 
+// resolve returns the model to be used for completion, falling back to the default model and applying model mapping.
+func (a Agent) resolve() string {
+	model := a.model
+	if model == "" {
+		model = defaultModel
+	}
+
+	return a.alias(model)
+}
+
+// alias applies model mapping to the model name.
+func (a Agent) alias(model string) string {
+	if m, ok := a.models[model]; ok {
+		return m
+	}
+
+	return model
+}
+
+// fallback returns resolved fallback models of the model, fallbacks may be defined for the model or for its alias.
+func (a Agent) fallback(model string) []string {
+	names, ok := a.fallbacks[model]
+	if !ok {
+		for primary, f := range a.fallbacks {
+			if a.alias(primary) == model {
+				names = f
+				break
+			}
+		}
+	}
+
+	models := make([]string, len(names))
+	for i, name := range names {
+		models[i] = a.alias(name)
+	}
+
+	return models
+}
+
+// stop tells if any of stop conditions is met.
+func (a Agent) stop(ctx context.Context, reply *AssistantMessage) (bool, error) {
+	for _, cond := range a.stops {
+		stop, err := cond(ctx, reply, a.memory)
+		if err != nil {
+			return false, err
+		}
+
+		if stop {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// observe notifies observers about completed iteration.
+func (a Agent) observe(ctx context.Context, event IterationEvent) {
+	for _, o := range a.observers {
+		o(ctx, event)
+	}
+}
+
+// stitch joins content of a truncated reply with its continuation, adjacent text blocks are merged into one.
+func stitch(head, tail []MessageBlock) []MessageBlock {
+	result := make([]MessageBlock, 0, len(head)+len(tail))
+	result = append(result, head...)
+
+	for _, block := range tail {
+		if n := len(result); n > 0 && block.Type == MessageBlockTypeText && result[n-1].Type == MessageBlockTypeText {
+			result[n-1].Text += block.Text
+			continue
+		}
+
+		result = append(result, block)
+	}
+
+	return result
+}
+
 func (a Agent) complete(ctx context.Context, req CompletionRequest) (resp *CompletionResponse, err error) {
 	span, ctx := tracing.StartSpan(ctx, "chat_completion", tracing.Kind(tracing.SpanLLM), tracing.Input(req.Messages), tracing.Attr("model", req.Model))
 	defer span.CloseWithError(err)
 
-	if m, ok := a.models[req.Model]; ok {
-		req.Model = m
-	}
-
 	if s, ok := a.memory.(Streamer); ok {
 		req.StreamCallback = s.Stream
 	}
 
 	resp, err = a.completer.Complete(ctx, req)
+
+	// downgrade the model if it's not available
+	for _, model := range a.fallback(req.Model) {
+		if err == nil || !unavailable(err) {
+			break
+		}
+
+		slog.WarnContext(ctx, "Model is not available, falling back to another model", "channel", "llm", "model", req.Model, "fallback", model, "error", err)
+
+		req.Model = model
+		req.IdempotencyKey = uuid.NewString() // the request body is different, so it's a new request for the provider
+
+		if limit, ok := ModelMaxTokens(model); ok && req.MaxTokens != nil && *req.MaxTokens > limit {
+			req.MaxTokens = &limit
+		}
+
+		span.SetMetadata("fallback_model", model)
+
+		resp, err = a.completer.Complete(ctx, req)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	// some completers do not report the model, the response is attributed to the model of the last attempt
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+
 	span.SetOutput(resp.Content)
 	span.SetMetric("tokens", float64(resp.Usage.TotalTokens))
 	span.SetMetric("prompt_tokens", float64(resp.Usage.PromptTokens))
@@ -191,17 +520,23 @@ func (a Agent) complete(ctx context.Context, req CompletionRequest) (resp *Compl
 	return resp, nil
 }
 
-func (a Agent) call(ctx context.Context, reply AssistantMessage) error {
+// call executes tool calls of the reply and writes down their results to memory, executed results are returned.
+func (a Agent) call(ctx context.Context, reply AssistantMessage) ([]Message, error) {
 	var undecided []ToolCall
 	approved := map[string]bool{}
 
+	defs := map[string]Tool{}
+	for _, tool := range a.tools.List() {
+		defs[tool.Name] = tool
+	}
+
 	// verify approvals for tool calls
 	for _, block := range reply.Content {
 		if block.Type != MessageBlockTypeToolCall {
 			continue
 		}
 
-		switch a.approve(*block.ToolCall) {
+		switch a.approve(*block.ToolCall, defs[block.ToolCall.Name]) {
 		case ToolCallUndecided:
 			undecided = append(undecided, *block.ToolCall)
 		case ToolCallApproved:
@@ -212,14 +547,22 @@ func (a Agent) call(ctx context.Context, reply AssistantMessage) error {
 	}
 
 	if len(undecided) > 0 {
-		return ToolApprovalRequest{Calls: undecided}
+		return nil, ToolApprovalRequest{Calls: undecided}
 	}
 
 	// execute all tool calls
 	results := make([]Message, len(reply.Content))
 
+	// shared state for tools
+	for key, value := range a.toolValues {
+		ctx = context.WithValue(ctx, key, value)
+	}
+
 	eg, gctx := errgroup.WithContext(ctx)
 	eg.SetLimit(a.parallelism)
+	if !a.parallelToolCalls() {
+		eg.SetLimit(1)
+	}
 
 	for index, block := range reply.Content {
 		if block.Type != MessageBlockTypeToolCall {
@@ -236,7 +579,19 @@ func (a Agent) call(ctx context.Context, reply AssistantMessage) error {
 			span, gctx := tracing.StartSpan(gctx, fmt.Sprintf("tool_call %q", call.Name), tracing.Kind(tracing.SpanTool), tracing.Input(args))
 			defer span.Close()
 
+			def, ok := defs[call.Name]
+			if !ok {
+				def = Tool{Name: call.Name}
+			}
+
+			span.SetTag(def.Tags...)
+			for k, v := range def.Metadata {
+				span.SetMetadata(k, v)
+			}
+
 			if s, ok := a.memory.(Streamer); ok {
+				gctx = context.WithValue(gctx, contextToolStream, toolStream{streamer: s, index: index, call: call})
+
 				_ = s.Stream(ctx, Chunk{Type: StreamChunkTypeToolCallExecute, Index: index, Call: &ToolCall{ID: call.ID, Name: call.Name}})
 				defer func() {
 					_ = s.Stream(ctx, Chunk{Type: StreamChunkTypeToolCallComplete, Index: index, Call: &ToolCall{ID: call.ID, Name: call.Name}})
@@ -245,54 +600,154 @@ func (a Agent) call(ctx context.Context, reply AssistantMessage) error {
 
 			var result any
 
-			if approved[call.ID] {
-				result, err = a.tools.Call(gctx, call.Name, []byte(args))
-			} else {
+			start := time.Now()
+
+			switch {
+			case !a.available(gctx, def):
+				err = fmt.Errorf("tool %q is not available", call.Name)
+			case !approved[call.ID]:
 				err = errors.New("tool call has been rejected by the user")
+			case gctx.Err() != nil:
+				// another call of the batch has handed over the conversation, the tool is not started
+				err = fmt.Errorf("tool call has been cancelled: %v", context.Cause(gctx))
+			default:
+				result, err = a.invoke(gctx, call.Name, []byte(args))
+
+				// the result of the tool interrupted by handoff may be partial, so it's not reported
+				if err == nil && gctx.Err() != nil {
+					err = fmt.Errorf("tool call has been cancelled: %v", context.Cause(gctx))
+				}
 			}
 
+			span.SetMetric("duration_ms", float64(time.Since(start).Milliseconds()))
+
 			if err != nil {
+				span.SetTag("error")
 				span.SetError(err)
-				if errors.As(err, &Handoff{}) {
+
+				var handoff Handoff
+				if errors.As(err, &handoff) {
+					target := "another agent"
+					if handoff.Agent != nil {
+						target = handoff.Agent.Name()
+					}
+
+					results[index] = NewToolResult(call.ID, fmt.Sprintf("The conversation is handed over to %s.", target))
+					return err
+				}
+
+				if errors.As(err, &abortError{}) {
 					return err
 				}
 
-				results[index] = NewToolError(call.ID, err.Error())
+				failure := NewToolError(call.ID, err.Error())
+				if a.errFormat != nil {
+					failure.Content = a.errFormat(call.Name, err)
+				}
+
+				results[index] = failure
 				return nil
 			}
 
+			for _, transform := range a.transforms {
+				result = transform(gctx, call.Name, result)
+			}
+
+			span.SetTag("success")
 			span.SetOutput(result)
 
-			results[index] = NewToolResult(call.ID, result)
+			switch out := result.(type) {
+			case ToolOutput:
+				results[index] = ToolResult{CallID: call.ID, Result: out.Result, Attachments: out.Attachments}
+			case *ToolOutput:
+				results[index] = ToolResult{CallID: call.ID, Result: out.Result, Attachments: out.Attachments}
+			default:
+				results[index] = NewToolResult(call.ID, result)
+			}
 
 			return nil
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
-		return err
+	// on handoff results are written down, so every tool call of the conversation handed over has a result
+	failure := eg.Wait()
+	if failure != nil && !errors.As(failure, &Handoff{}) {
+		return nil, failure
 	}
 
 	// write down tool execution results
+	var executed []Message
 	var errs []error
 	for _, result := range results {
 		if result == nil {
 			continue
 		}
 
+		if a.maxResult > 0 {
+			result = limitResult(result, a.maxResult)
+		}
+
+		executed = append(executed, result)
+
 		if err := a.memory.Append(ctx, result); err != nil {
 			errs = append(errs, err)
 		}
 	}
 
+	// the message of the handoff follows tool results, providers reject messages between tool calls and their results
+	var handoff Handoff
+	if errors.As(failure, &handoff) && handoff.Message != "" {
+		if err := a.memory.Append(ctx, NewAssistantMessage(handoff.Message)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if len(errs) > 0 {
-		return errors.Join(errs...)
+		return executed, errors.Join(append([]error{failure}, errs...)...)
+	}
+
+	return executed, failure
+}
+
+// invoke calls the tool, retrying temporary failures according to the retry policy of the tool.
+func (a Agent) invoke(ctx context.Context, name string, args []byte) (any, error) {
+	policy := a.retries[name]
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		result, err := a.attempt(ctx, name, args)
+		if err == nil || attempt >= policy.Attempts || !temporary(err) {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// attempt calls the tool once, the call is limited by the tool timeout.
+func (a Agent) attempt(ctx context.Context, name string, args []byte) (any, error) {
+	if a.toolTimeout <= 0 {
+		return a.tools.Call(ctx, name, args)
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(ctx, a.toolTimeout)
+	defer cancel()
+
+	result, err := a.tools.Call(ctx, name, args)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("tool call has timed out after %s: %w", a.toolTimeout, err)
+	}
+
+	return result, err
 }
 
-func (a Agent) approve(call ToolCall) ToolCallApproval {
+func (a Agent) approve(call ToolCall, tool Tool) ToolCallApproval {
 	approved := false
 	for _, p := range a.approver {
 		switch p(call) {
@@ -305,6 +760,15 @@ func (a Agent) approve(call ToolCall) ToolCallApproval {
 		}
 	}
 
+	// tools with protected tags are not approved automatically
+	if approved && !a.approvals[call.ID] {
+		for _, tag := range tool.Tags {
+			if a.protected[tag] {
+				return ToolCallUndecided
+			}
+		}
+	}
+
 	if approved {
 		return ToolCallApproved
 	}
@@ -312,6 +776,25 @@ func (a Agent) approve(call ToolCall) ToolCallApproval {
 	return ToolCallUndecided
 }
 
+// available checks if the tool is enabled for this run.
+func (a Agent) available(ctx context.Context, tool Tool) bool {
+	if a.enabled != nil && !a.enabled[tool.Name] {
+		return false
+	}
+
+	if a.disabled[tool.Name] {
+		return false
+	}
+
+	for _, filter := range a.filters {
+		if !filter(ctx, tool) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // clone creates a deep copy of the agent to avoid shared state between concurrent calls
 func (a Agent) clone() Agent {
 	c := Agent{
@@ -323,6 +806,50 @@ func (a Agent) clone() Agent {
 		model:       a.model,
 		iterations:  a.iterations,
 		parallelism: a.parallelism,
+		parallel:    a.parallel,
+		semaphore:   a.semaphore,
+		continues:   a.continues,
+		loopLimit:   a.loopLimit,
+		loopAction:  a.loopAction,
+		maxResult:   a.maxResult,
+		toolTimeout: a.toolTimeout,
+		errFormat:   a.errFormat,
+		maxCost:     a.maxCost,
+		pricing:     a.pricing,
+		temperature: a.temperature,
+		maxTokens:   a.maxTokens,
+		topP:        a.topP,
+		topK:        a.topK,
+		seed:        a.seed,
+		useCache:    a.useCache,
+		cachePrefix: a.cachePrefix,
+		logProbs:    a.logProbs,
+		traceProj:   a.traceProj,
+		runID:       a.runID,
+		noReserved:  a.noReserved,
+		topLogProbs: a.topLogProbs,
+	}
+
+	if a.values != nil {
+		c.values = make(map[string]any, len(a.values))
+		for k, v := range a.values {
+			c.values[k] = v
+		}
+	}
+
+	if a.defaults != nil {
+		c.defaults = make(map[string]any, len(a.defaults))
+		for k, v := range a.defaults {
+			c.defaults[k] = v
+		}
+	}
+
+	// tools added for a single run should not leak into the agent
+	switch t := a.tools.(type) {
+	case *StaticToolset:
+		c.tools = t.clone()
+	case *MergedToolset:
+		c.tools = t.clone()
 	}
 
 	if a.messages != nil {
@@ -330,6 +857,35 @@ func (a Agent) clone() Agent {
 		copy(c.messages, a.messages)
 	}
 
+	if a.extra != nil {
+		c.extra = make(map[string]any, len(a.extra))
+		for k, v := range a.extra {
+			c.extra[k] = v
+		}
+	}
+
+	if a.toolValues != nil {
+		c.toolValues = make(map[any]any, len(a.toolValues))
+		for k, v := range a.toolValues {
+			c.toolValues[k] = v
+		}
+	}
+
+	if a.ephemeral != nil {
+		c.ephemeral = make([]Message, len(a.ephemeral))
+		copy(c.ephemeral, a.ephemeral)
+	}
+
+	if a.sysPrefix != nil {
+		c.sysPrefix = make([]string, len(a.sysPrefix))
+		copy(c.sysPrefix, a.sysPrefix)
+	}
+
+	if a.sysSuffix != nil {
+		c.sysSuffix = make([]string, len(a.sysSuffix))
+		copy(c.sysSuffix, a.sysSuffix)
+	}
+
 	if a.models != nil {
 		c.models = make(map[string]string, len(a.models))
 		for k, v := range a.models {
@@ -360,16 +916,93 @@ func (a Agent) clone() Agent {
 		}
 	}
 
+	if a.format != nil {
+		format := *a.format
+		c.format = &format
+	}
+
+	if a.retries != nil {
+		c.retries = make(map[string]ToolRetry, len(a.retries))
+		for k, v := range a.retries {
+			c.retries[k] = v
+		}
+	}
+
+	if a.enabled != nil {
+		c.enabled = make(map[string]bool, len(a.enabled))
+		for k, v := range a.enabled {
+			c.enabled[k] = v
+		}
+	}
+
+	if a.disabled != nil {
+		c.disabled = make(map[string]bool, len(a.disabled))
+		for k, v := range a.disabled {
+			c.disabled[k] = v
+		}
+	}
+
 	if a.dynamics != nil {
 		c.dynamics = make([]OptionLoader, len(a.dynamics))
 		copy(c.dynamics, a.dynamics)
 	}
 
+	if a.fallbacks != nil {
+		c.fallbacks = make(map[string][]string, len(a.fallbacks))
+		for k, v := range a.fallbacks {
+			c.fallbacks[k] = v
+		}
+	}
+
+	if a.approvals != nil {
+		c.approvals = make(map[string]bool, len(a.approvals))
+		for k, v := range a.approvals {
+			c.approvals[k] = v
+		}
+	}
+
+	if a.protected != nil {
+		c.protected = make(map[string]bool, len(a.protected))
+		for k, v := range a.protected {
+			c.protected[k] = v
+		}
+	}
+
 	if a.approver != nil {
 		c.approver = make([]func(call ToolCall) ToolCallApproval, len(a.approver))
 		copy(c.approver, a.approver)
 	}
 
+	if a.traceTags != nil {
+		c.traceTags = make([]string, len(a.traceTags))
+		copy(c.traceTags, a.traceTags)
+	}
+
+	if a.preprocess != nil {
+		c.preprocess = make([]func(messages []Message) []Message, len(a.preprocess))
+		copy(c.preprocess, a.preprocess)
+	}
+
+	if a.observers != nil {
+		c.observers = make([]Observer, len(a.observers))
+		copy(c.observers, a.observers)
+	}
+
+	if a.filters != nil {
+		c.filters = make([]func(context.Context, Tool) bool, len(a.filters))
+		copy(c.filters, a.filters)
+	}
+
+	if a.transforms != nil {
+		c.transforms = make([]ToolResultTransformer, len(a.transforms))
+		copy(c.transforms, a.transforms)
+	}
+
+	if a.stops != nil {
+		c.stops = make([]StopCondition, len(a.stops))
+		copy(c.stops, a.stops)
+	}
+
 	if a.finalizer != nil {
 		c.finalizer = make([]func(reply *AssistantMessage) error, len(a.finalizer))
 		copy(c.finalizer, a.finalizer)
@@ -377,3 +1010,50 @@ func (a Agent) clone() Agent {
 
 	return c
 }
+
+// parallelToolCalls tells if the model may request and the agent may execute several tool calls at once.
+func (a Agent) parallelToolCalls() bool {
+	if a.parallel != nil {
+		return *a.parallel
+	}
+
+	return a.parallelism != 1 && a.parallelism != 0
+}
+
+// starter returns starter messages with system prefix and suffix added to the first and the last system message. If
+// there is no system message, prefix and suffix make one.
+func (a Agent) starter() []Message {
+	if len(a.sysPrefix) == 0 && len(a.sysSuffix) == 0 {
+		return a.messages
+	}
+
+	first, last := -1, -1
+	for i, m := range a.messages {
+		if _, ok := m.(SystemMessage); ok {
+			if first < 0 {
+				first = i
+			}
+
+			last = i
+		}
+	}
+
+	if first < 0 {
+		text := strings.Join(append(append([]string(nil), a.sysPrefix...), a.sysSuffix...), "\n\n")
+		return append([]Message{SystemMessage{Content: text}}, a.messages...)
+	}
+
+	messages := append([]Message(nil), a.messages...)
+
+	if len(a.sysPrefix) > 0 {
+		m := messages[first].(SystemMessage)
+		messages[first] = SystemMessage{Content: strings.Join(append(append([]string(nil), a.sysPrefix...), m.Content), "\n\n")}
+	}
+
+	if len(a.sysSuffix) > 0 {
+		m := messages[last].(SystemMessage)
+		messages[last] = SystemMessage{Content: strings.Join(append([]string{m.Content}, a.sysSuffix...), "\n\n")}
+	}
+
+	return messages
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	search := WithInlineTool("search", "Search the web", func(ctx context.Context, in struct {
+		Query string `json:"query"`
+	}) (string, error) {
+		return "found " + in.Query, nil
+	})
+
+	tests := map[string]struct {
+		opts      []Option
+		responses []scripted
+		want      string // text of the reply
+		err       error
+		requests  int // number of completion requests
+		memory    int // number of messages stored in memory
+		check     func(t *testing.T, requests []CompletionRequest, memory []Message)
+	}{
+		"text reply": {
+			responses: []scripted{{resp: text("Hello")}},
+			want:      "Hello",
+			requests:  1,
+			memory:    1,
+		},
+		"tool call and reply": {
+			responses: []scripted{{resp: calls(searchCall("1", "go"))}, {resp: text("Done")}},
+			want:      "Done",
+			requests:  2,
+			memory:    3,
+			check: func(t *testing.T, requests []CompletionRequest, memory []Message) {
+				if r, ok := memory[1].(ToolResult); !ok || r.String() != "found go" {
+					t.Errorf("Expected tool result to be stored, got %#v", memory[1])
+				}
+			},
+		},
+		"empty reply is not stored": {
+			responses: []scripted{{resp: text(" ")}},
+			err:       ErrEmptyResponse,
+			requests:  1,
+			memory:    0,
+		},
+		"continuation of truncated reply": {
+			opts:      []Option{WithContinueOnLength(1)},
+			responses: []scripted{{resp: truncated(text("Hel"))}, {resp: text("lo")}},
+			want:      "Hello",
+			requests:  2,
+			memory:    3,
+		},
+		"continuations are limited": {
+			opts:      []Option{WithContinueOnLength(1)},
+			responses: []scripted{{resp: truncated(text("Hel"))}, {resp: truncated(text("lo"))}},
+			want:      "Hello",
+			requests:  2,
+			memory:    3,
+		},
+		"truncated tool calls": {
+			opts:      []Option{WithContinueOnLength(1)},
+			responses: []scripted{{resp: truncated(calls(searchCall("1", "go")))}},
+			err:       ErrTruncatedToolCalls,
+			requests:  1,
+			memory:    0,
+		},
+		"stop condition": {
+			opts: []Option{WithStopCondition(func(ctx context.Context, reply *AssistantMessage, memory Memory) (bool, error) {
+				return true, nil
+			})},
+			responses: []scripted{{resp: calls(searchCall("1", "go"))}, {resp: text("Never")}},
+			requests:  1,
+			memory:    2,
+		},
+		"budget": {
+			opts:      []Option{WithMaxCost(0.5, Pricing{"model": {Prompt: 1_000_000}})},
+			responses: []scripted{{resp: usage(calls(searchCall("1", "go")), 1)}, {resp: text("Never")}},
+			err:       ErrBudgetExceeded,
+			requests:  1,
+			memory:    2,
+		},
+		"loop is aborted": {
+			opts:      []Option{WithLoopDetection(2, LoopActionAbort)},
+			responses: []scripted{{resp: calls(searchCall("1", "go"))}, {resp: calls(searchCall("2", "go"))}, {resp: text("Never")}},
+			err:       ErrStuckLoop,
+			requests:  2,
+			memory:    4,
+		},
+		"parallel identical calls are not a loop": {
+			opts:      []Option{WithLoopDetection(2, LoopActionAbort)},
+			responses: []scripted{{resp: calls(searchCall("1", "go"), searchCall("2", "go"))}, {resp: text("Done")}},
+			want:      "Done",
+			requests:  2,
+			memory:    4,
+		},
+		"loop is nudged": {
+			opts:      []Option{WithLoopDetection(2, LoopActionNudge)},
+			responses: []scripted{{resp: calls(searchCall("1", "go"))}, {resp: calls(searchCall("2", "go"))}, {resp: text("Done")}},
+			want:      "Done",
+			requests:  3,
+			memory:    6,
+			check: func(t *testing.T, requests []CompletionRequest, memory []Message) {
+				messages := requests[2].Messages
+				if m, ok := messages[len(messages)-1].(UserMessage); !ok || !strings.Contains(m.Content, "same arguments") {
+					t.Errorf("Expected the last request to end with a nudge, got %#v", messages[len(messages)-1])
+				}
+			},
+		},
+		"fallback model": {
+			opts: []Option{
+				WithModel("primary"),
+				WithModelMapper(map[string]string{"primary": "gpt-4o", "backup": "claude-3-haiku"}),
+				WithModelFallback("primary", "backup"),
+				WithMaxTokens(10000),
+			},
+			responses: []scripted{{err: NewAPIError(529, "overloaded_error", "overloaded", nil)}, {resp: text("Hello")}},
+			want:      "Hello",
+			requests:  2,
+			memory:    1,
+			check: func(t *testing.T, requests []CompletionRequest, memory []Message) {
+				if requests[0].Model != "gpt-4o" || requests[1].Model != "claude-3-haiku" {
+					t.Errorf("Expected models to be resolved by mapping, got %q and %q", requests[0].Model, requests[1].Model)
+				}
+
+				if got := *requests[1].MaxTokens; got != 4096 {
+					t.Errorf("Expected max tokens to be clamped to the limit of the fallback model, got %d", got)
+				}
+
+				if requests[0].IdempotencyKey == requests[1].IdempotencyKey {
+					t.Error("Expected fallback request to have a new idempotency key")
+				}
+			},
+		},
+		"fallback is not used for other errors": {
+			opts: []Option{
+				WithModel("primary"),
+				WithModelFallback("primary", "backup"),
+			},
+			responses: []scripted{{err: NewAPIError(400, "invalid_request_error", "invalid", nil)}, {resp: text("Never")}},
+			err:       &APIError{},
+			requests:  1,
+			memory:    0,
+		},
+		"fallback is priced by the model which answered": {
+			opts: []Option{
+				WithModel("gpt-4o"),
+				WithModelFallback("gpt-4o", "claude-3-haiku"),
+				WithMaxCost(5, Pricing{"gpt-4o": {}, "claude-3-haiku": {Prompt: 1_000_000}}),
+			},
+			responses: []scripted{{err: NewAPIError(529, "overloaded_error", "overloaded", nil)}, {resp: usage(calls(searchCall("1", "go")), 10)}, {resp: text("Never")}},
+			err:       ErrBudgetExceeded,
+			requests:  2,
+			memory:    2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			completer := &scriptCompleter{responses: tc.responses}
+			memory := NewStaticMemory()
+
+			a := New("assistant", append([]Option{
+				WithChatCompleter(completer),
+				WithModel("model"),
+				WithMemory(memory),
+				search,
+				WithAutoApproveTools("search"),
+			}, tc.opts...)...)
+
+			reply, err := a.Run(context.Background(), WithUserMessage("Search go"))
+
+			switch want := tc.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+			case *APIError:
+				if !errors.As(err, &want) {
+					t.Fatalf("Run() error = %v, want %T", err, want)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("Run() error = %v, want %v", err, want)
+				}
+			}
+
+			if tc.err == nil && reply.Text() != tc.want {
+				t.Errorf("Run() reply = %q, want %q", reply.Text(), tc.want)
+			}
+
+			if got := len(completer.requests); got != tc.requests {
+				t.Errorf("Expected %d completion requests, got %d", tc.requests, got)
+			}
+
+			if got := len(memory.List()); got != tc.memory {
+				t.Errorf("Expected %d messages in memory, got %d: %#v", tc.memory, got, memory.List())
+			}
+
+			if tc.check != nil {
+				tc.check(t, completer.requests, memory.List())
+			}
+		})
+	}
+}
+
+// scripted is a response or an error returned by scriptCompleter.
+type scripted struct {
+	resp *CompletionResponse
+	err  error
+}
+
+// scriptCompleter returns scripted responses in order and records requests.
+type scriptCompleter struct {
+	lock      sync.Mutex
+	responses []scripted
+	requests  []CompletionRequest
+}
+
+func (c *scriptCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.requests = append(c.requests, req)
+
+	if len(c.responses) == 0 {
+		return nil, errors.New("no more scripted responses")
+	}
+
+	next := c.responses[0]
+	c.responses = c.responses[1:]
+
+	return next.resp, next.err
+}
+
+func text(s string) *CompletionResponse {
+	return &CompletionResponse{Content: []MessageBlock{{Type: MessageBlockTypeText, Text: s}}, FinishReason: FinishReasonStop}
+}
+
+func calls(cc ...ToolCall) *CompletionResponse {
+	resp := &CompletionResponse{FinishReason: FinishReasonToolCalls}
+	for i := range cc {
+		resp.Content = append(resp.Content, MessageBlock{Type: MessageBlockTypeToolCall, ToolCall: &cc[i]})
+	}
+
+	return resp
+}
+
+func searchCall(id, query string) ToolCall {
+	return ToolCall{ID: id, Name: "search", Arguments: `{"query":"` + query + `"}`}
+}
+
+func truncated(resp *CompletionResponse) *CompletionResponse {
+	resp.FinishReason = FinishReasonLength
+	return resp
+}
+
+func usage(resp *CompletionResponse, promptTokens int) *CompletionResponse {
+	resp.Usage = CompletionUsage{PromptTokens: promptTokens, TotalTokens: promptTokens}
+	return resp
+}
@@ -0,0 +1,79 @@
+package anthropic
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/eolymp/go-agent"
+)
+
+// toolResultContent converts tool result and its attachments to content of Anthropic tool result block.
+func toolResultContent(m agent.ToolResult) []anthropic.ToolResultBlockParamContentUnion {
+	content := []anthropic.ToolResultBlockParamContentUnion{{OfText: &anthropic.TextBlockParam{Text: m.String()}}}
+
+	for _, a := range m.Attachments {
+		switch {
+		case a.Type == agent.AttachmentTypeImage:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfImage: &anthropic.ImageBlockParam{
+				Source: anthropic.ImageBlockParamSourceUnion{OfBase64: &anthropic.Base64ImageSourceParam{
+					Data:      base64.StdEncoding.EncodeToString(a.Data),
+					MediaType: anthropic.Base64ImageSourceMediaType(a.MediaType),
+				}},
+			}})
+		case a.Type == agent.AttachmentTypeDocument && a.MediaType == "application/pdf":
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfDocument: &anthropic.DocumentBlockParam{
+				Source: anthropic.DocumentBlockParamSourceUnion{OfBase64: &anthropic.Base64PDFSourceParam{
+					Data: base64.StdEncoding.EncodeToString(a.Data),
+				}},
+			}})
+		case a.Type == agent.AttachmentTypeDocument && a.MediaType == "text/plain":
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfDocument: &anthropic.DocumentBlockParam{
+				Source: anthropic.DocumentBlockParamSourceUnion{OfText: &anthropic.PlainTextSourceParam{
+					Data: string(a.Data),
+				}},
+			}})
+		default:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfText: &anthropic.TextBlockParam{Text: unsupported(a)}})
+		}
+	}
+
+	return content
+}
+
+// betaToolResultContent converts tool result and its attachments to content of Anthropic beta tool result block.
+func betaToolResultContent(m agent.ToolResult) []anthropic.BetaToolResultBlockParamContentUnion {
+	content := []anthropic.BetaToolResultBlockParamContentUnion{{OfText: &anthropic.BetaTextBlockParam{Type: "text", Text: m.String()}}}
+
+	for _, a := range m.Attachments {
+		switch {
+		case a.Type == agent.AttachmentTypeImage:
+			content = append(content, anthropic.BetaToolResultBlockParamContentUnion{OfImage: &anthropic.BetaImageBlockParam{
+				Source: anthropic.BetaImageBlockParamSourceUnion{OfBase64: &anthropic.BetaBase64ImageSourceParam{
+					Data:      base64.StdEncoding.EncodeToString(a.Data),
+					MediaType: anthropic.BetaBase64ImageSourceMediaType(a.MediaType),
+				}},
+			}})
+		case a.Type == agent.AttachmentTypeDocument && a.MediaType == "application/pdf":
+			content = append(content, anthropic.BetaToolResultBlockParamContentUnion{OfDocument: &anthropic.BetaRequestDocumentBlockParam{
+				Source: anthropic.BetaRequestDocumentBlockSourceUnionParam{OfBase64: &anthropic.BetaBase64PDFSourceParam{
+					Data: base64.StdEncoding.EncodeToString(a.Data),
+				}},
+			}})
+		case a.Type == agent.AttachmentTypeDocument && a.MediaType == "text/plain":
+			content = append(content, anthropic.BetaToolResultBlockParamContentUnion{OfDocument: &anthropic.BetaRequestDocumentBlockParam{
+				Source: anthropic.BetaRequestDocumentBlockSourceUnionParam{OfText: &anthropic.BetaPlainTextSourceParam{
+					Data: string(a.Data),
+				}},
+			}})
+		default:
+			content = append(content, anthropic.BetaToolResultBlockParamContentUnion{OfText: &anthropic.BetaTextBlockParam{Type: "text", Text: unsupported(a)}})
+		}
+	}
+
+	return content
+}
+
+func unsupported(a agent.Attachment) string {
+	return fmt.Sprintf("[attachment %q of type %s is not supported]", a.Name, a.MediaType)
+}
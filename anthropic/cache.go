@@ -0,0 +1,45 @@
+package anthropic
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/eolymp/go-agent"
+)
+
+// cachePrefix sets a cache breakpoint at the last block added for the message, so the conversation up to the message
+// is cached, see agent.CompletionRequest.CachedPrefix.
+func cachePrefix(params *anthropic.MessageNewParams, msg agent.Message) {
+	switch msg.(type) {
+	case agent.SystemMessage, agent.DeveloperMessage:
+		if n := len(params.System); n > 0 {
+			params.System[n-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+		}
+	default:
+		if n := len(params.Messages); n > 0 {
+			content := params.Messages[n-1].Content
+			if m := len(content); m > 0 {
+				if cc := content[m-1].GetCacheControl(); cc != nil {
+					*cc = anthropic.NewCacheControlEphemeralParam()
+				}
+			}
+		}
+	}
+}
+
+// betaCachePrefix is the same as cachePrefix, but for the beta API.
+func betaCachePrefix(params *anthropic.BetaMessageNewParams, msg agent.Message) {
+	switch msg.(type) {
+	case agent.SystemMessage, agent.DeveloperMessage:
+		if n := len(params.System); n > 0 {
+			params.System[n-1].CacheControl = anthropic.NewBetaCacheControlEphemeralParam()
+		}
+	default:
+		if n := len(params.Messages); n > 0 {
+			content := params.Messages[n-1].Content
+			if m := len(content); m > 0 {
+				if cc := content[m-1].GetCacheControl(); cc != nil {
+					*cc = anthropic.NewBetaCacheControlEphemeralParam()
+				}
+			}
+		}
+	}
+}
@@ -35,33 +35,87 @@ func NewWithClient(client anthropic.Client) *Completer {
 // Complete implements ChatCompleter by delegating to the Anthropic client.
 func (c *Completer) Complete(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
 	if req.StreamCallback != nil {
-		if len(req.Betas) > 0 || req.Container != nil || req.Reasoning != nil {
+		if useBeta(req) {
 			return c.betaStream(ctx, req)
 		}
 
 		return c.stream(ctx, req)
 	}
 
-	if len(req.Betas) > 0 || req.Container != nil || req.Reasoning != nil {
-		resp, err := c.client.Beta.Messages.New(ctx, toBetaAnthropicRequest(req))
+	if useBeta(req) {
+		params, err := toBetaAnthropicRequest(req)
 		if err != nil {
 			return nil, err
 		}
 
+		resp, err := c.client.Beta.Messages.New(ctx, params, requestOptions(req)...)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
 		return fromBetaAnthropicResponse(ctx, resp), nil
 	}
 
-	resp, err := c.client.Messages.New(ctx, toAnthropicRequest(req))
+	if tool, ok := formatTool(req); ok {
+		params, err := toFormatRequest(req, tool)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Messages.New(ctx, params, requestOptions(req)...)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		return fromFormatResponse(fromAnthropicResponse(ctx, resp), tool), nil
+	}
+
+	params, err := toAnthropicRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := c.client.Messages.New(ctx, params, requestOptions(req)...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
 	return fromAnthropicResponse(ctx, resp), nil
 }
 
+// Ping implements agent.Pinger by listing available models.
+func (c *Completer) Ping(ctx context.Context) error {
+	if _, err := c.client.Models.List(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1)}); err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// useBeta tells if the request must be sent to the beta API: it uses beta features, container, extended thinking or
+// built-in server tools (results of server tools are only parsed from beta responses).
+func useBeta(req agent.CompletionRequest) bool {
+	if len(req.Betas) > 0 || req.Container != nil || req.Reasoning != nil {
+		return true
+	}
+
+	for _, tool := range req.Tools {
+		if tool.DeferLoading || (tool.Type != "" && tool.Type != "function") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // stream handles streaming completion with callback support.
 func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
-	stream := c.client.Messages.NewStreaming(ctx, toAnthropicRequest(req))
+	params, err := toAnthropicRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := c.client.Messages.NewStreaming(ctx, params, requestOptions(req)...)
 	defer stream.Close()
 
 	resp := &agent.CompletionResponse{}
@@ -96,6 +150,35 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 					Call:  block.ToolCall,
 				}
 
+				if err := req.StreamCallback(ctx, chunk); err != nil {
+					return nil, err
+				}
+			case "server_tool_use":
+				block.Type = agent.MessageBlockTypeServerToolCall
+				block.ToolCall = &agent.ToolCall{
+					ID:   event.ContentBlock.ID,
+					Name: event.ContentBlock.Name,
+				}
+
+				chunk := agent.Chunk{
+					Type:  agent.StreamChunkTypeServerToolCallStart,
+					Index: index,
+					Call:  &agent.ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name},
+				}
+
+				if err := req.StreamCallback(ctx, chunk); err != nil {
+					return nil, err
+				}
+			case "web_search_tool_result":
+				block.Type = agent.MessageBlockTypeToolResult
+				block.ToolResult = &agent.ToolResult{CallID: event.ContentBlock.ToolUseID, Result: event.ContentBlock.JSON.Content.Raw()}
+
+				chunk := agent.Chunk{
+					Type:   agent.StreamChunkTypeToolResult,
+					Index:  index,
+					Result: block.ToolResult,
+				}
+
 				if err := req.StreamCallback(ctx, chunk); err != nil {
 					return nil, err
 				}
@@ -129,13 +212,19 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 					return nil, err
 				}
 			case "input_json_delta":
-				if block.Type != agent.MessageBlockTypeToolCall {
+				var kind agent.StreamChunkType
+				switch block.Type {
+				case agent.MessageBlockTypeToolCall:
+					kind = agent.StreamChunkTypeToolCallDelta
+				case agent.MessageBlockTypeServerToolCall:
+					kind = agent.StreamChunkTypeServerToolCallDelta
+				default:
 					continue
 				}
 
 				block.ToolCall.Arguments += event.Delta.PartialJSON
 				chunk := agent.Chunk{
-					Type:  agent.StreamChunkTypeToolCallDelta,
+					Type:  kind,
 					Index: index,
 					Call:  &agent.ToolCall{ID: block.ToolCall.ID, Name: block.ToolCall.Name, Arguments: event.Delta.PartialJSON},
 				}
@@ -155,19 +244,21 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 				resp.FinishReason = mapFinishReason(event.Delta.StopReason)
 			}
 
+			usage := resp.Usage
 			chunk := agent.Chunk{
 				Type:  agent.StreamChunkTypeUsage,
-				Usage: &resp.Usage,
+				Usage: &usage,
 			}
 
 			if err := req.StreamCallback(ctx, chunk); err != nil {
 				return nil, err
 			}
 		case "message_stop":
-			reason := resp.FinishReason
+			usage := resp.Usage
 			chunk := agent.Chunk{
 				Type:         agent.StreamChunkTypeFinish,
-				FinishReason: reason,
+				FinishReason: resp.FinishReason,
+				Usage:        &usage,
 			}
 
 			if err := req.StreamCallback(ctx, chunk); err != nil {
@@ -179,7 +270,7 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 	}
 
 	if err := stream.Err(); err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	length := -1
@@ -198,7 +289,12 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 }
 
 func (c *Completer) betaStream(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
-	stream := c.client.Beta.Messages.NewStreaming(ctx, toBetaAnthropicRequest(req))
+	params, err := toBetaAnthropicRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := c.client.Beta.Messages.NewStreaming(ctx, params, requestOptions(req)...)
 	defer stream.Close()
 
 	resp := &agent.CompletionResponse{}
@@ -381,9 +477,10 @@ func (c *Completer) betaStream(ctx context.Context, req agent.CompletionRequest)
 				resp.FinishReason = mapBetaFinishReason(event.Delta.StopReason)
 			}
 
+			usage := resp.Usage
 			chunk := agent.Chunk{
 				Type:  agent.StreamChunkTypeUsage,
-				Usage: &resp.Usage,
+				Usage: &usage,
 			}
 
 			if err := req.StreamCallback(ctx, chunk); err != nil {
@@ -391,10 +488,11 @@ func (c *Completer) betaStream(ctx context.Context, req agent.CompletionRequest)
 			}
 
 		case "message_stop":
-			reason := resp.FinishReason
+			usage := resp.Usage
 			chunk := agent.Chunk{
 				Type:         agent.StreamChunkTypeFinish,
-				FinishReason: reason,
+				FinishReason: resp.FinishReason,
+				Usage:        &usage,
 			}
 
 			if err := req.StreamCallback(ctx, chunk); err != nil {
@@ -407,7 +505,7 @@ func (c *Completer) betaStream(ctx context.Context, req agent.CompletionRequest)
 	}
 
 	if err := stream.Err(); err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	length := -1
@@ -426,8 +524,8 @@ func (c *Completer) betaStream(ctx context.Context, req agent.CompletionRequest)
 }
 
 // toAnthropicRequest converts a universal CompletionRequest to Anthropic-specific params.
-func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams {
-	params := anthropic.MessageNewParams{Model: anthropic.Model(req.Model), MaxTokens: 8192}
+func toAnthropicRequest(req agent.CompletionRequest) (anthropic.MessageNewParams, error) {
+	params := anthropic.MessageNewParams{Model: anthropic.Model(req.Model), MaxTokens: defaultMaxTokens(req.Model)}
 
 	if req.MaxTokens != nil {
 		params.MaxTokens = *req.MaxTokens
@@ -437,7 +535,8 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 		params.Temperature = param.NewOpt(float64(*req.Temperature))
 	}
 
-	if req.TopP != nil {
+	// top_p of 1 is the default, it's not sent because recent models do not accept both temperature and top_p
+	if req.TopP != nil && *req.TopP != 1 {
 		params.TopP = param.NewOpt(float64(*req.TopP))
 	}
 
@@ -445,12 +544,14 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 		params.TopK = param.NewOpt(int64(*req.TopK))
 	}
 
+	// Note: Anthropic does not support log probabilities and seed, req.LogProbs, req.TopLogProbs and req.Seed are ignored
+
 	if req.UseCache != nil && *req.UseCache {
 		params.CacheControl = anthropic.NewCacheControlEphemeralParam()
 	}
 
 	// Convert messages - separate system messages from conversation messages
-	for _, msg := range req.Messages {
+	for i, msg := range req.Messages {
 		switch m := msg.(type) {
 		case agent.SystemMessage:
 			params.System = append(params.System, anthropic.TextBlockParam{
@@ -458,6 +559,12 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 				Text: m.Content,
 			})
 
+		case agent.DeveloperMessage:
+			params.System = append(params.System, anthropic.TextBlockParam{
+				Type: "text",
+				Text: m.Content,
+			})
+
 		case agent.UserMessage:
 			params.Messages = append(params.Messages, anthropic.MessageParam{
 				Role:    "user",
@@ -487,8 +594,12 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 
 		case agent.ToolResult:
 			params.Messages = append(params.Messages, anthropic.MessageParam{
-				Role:    "user",
-				Content: []anthropic.ContentBlockParamUnion{anthropic.NewToolResultBlock(m.CallID, m.String(), false)},
+				Role: "user",
+				Content: []anthropic.ContentBlockParamUnion{{OfToolResult: &anthropic.ToolResultBlockParam{
+					ToolUseID: m.CallID,
+					IsError:   anthropic.Bool(false),
+					Content:   toolResultContent(m),
+				}}},
 			})
 
 		case agent.ToolError:
@@ -497,11 +608,29 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 				Content: []anthropic.ContentBlockParamUnion{anthropic.NewToolResultBlock(m.CallID, m.String(), true)},
 			})
 		}
+
+		if i == req.CachedPrefix-1 {
+			cachePrefix(&params, msg)
+		}
+	}
+
+	if req.ResponseFormat != nil {
+		instruction, err := formatInstruction(*req.ResponseFormat)
+		if err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
+
+		params.System = append(params.System, anthropic.TextBlockParam{Type: "text", Text: instruction})
 	}
 
 	// Convert tools if present
 	if len(req.Tools) > 0 {
-		params.Tools = toAnthropicTools(req.Tools)
+		tools, err := toAnthropicTools(req.Tools)
+		if err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
+
+		params.Tools = tools
 
 		// Convert tool choice
 		switch req.ToolChoice {
@@ -521,7 +650,7 @@ func toAnthropicRequest(req agent.CompletionRequest) anthropic.MessageNewParams
 		}
 	}
 
-	return params
+	return params, nil
 }
 
 // fromAnthropicResponse converts an Anthropic response to a universal CompletionResponse.
@@ -554,6 +683,16 @@ func fromAnthropicResponse(ctx context.Context, resp *anthropic.Message) *agent.
 					Arguments: string(b.Input),
 				},
 			}
+		case "server_tool_use":
+			ar.Content[i] = agent.MessageBlock{
+				Type:     agent.MessageBlockTypeServerToolCall,
+				ToolCall: &agent.ToolCall{ID: b.ID, Name: b.Name, Arguments: string(b.Input)},
+			}
+		case "web_search_tool_result":
+			ar.Content[i] = agent.MessageBlock{
+				Type:       agent.MessageBlockTypeToolResult,
+				ToolResult: &agent.ToolResult{CallID: b.ToolUseID, Result: b.JSON.Content.Raw()},
+			}
 		default:
 			slog.WarnContext(ctx, "Unknown content block type", "channel", "llm", "type", b.Type)
 		}
@@ -563,7 +702,7 @@ func fromAnthropicResponse(ctx context.Context, resp *anthropic.Message) *agent.
 }
 
 // toAnthropicTools converts internal tools to Anthropic tool params.
-func toAnthropicTools(tools []agent.Tool) []anthropic.ToolUnionParam {
+func toAnthropicTools(tools []agent.Tool) ([]anthropic.ToolUnionParam, error) {
 	result := make([]anthropic.ToolUnionParam, len(tools))
 
 	for i, tool := range tools {
@@ -583,19 +722,49 @@ func toAnthropicTools(tools []agent.Tool) []anthropic.ToolUnionParam {
 
 		if tool.InputSchema != nil && tool.InputSchema.Type != "" {
 			if tool.InputSchema.Type != "object" {
-				panic(fmt.Errorf("tool %q input schema must be object", tool.Name))
+				return nil, fmt.Errorf("tool %q input schema must be object", tool.Name)
+			}
+
+			extras, err := schemaExtras(tool)
+			if err != nil {
+				return nil, err
 			}
 
 			t.InputSchema = anthropic.ToolInputSchemaParam{
-				Properties: tool.InputSchema.Properties,
-				Required:   tool.InputSchema.Required,
+				Properties:  tool.InputSchema.Properties,
+				Required:    tool.InputSchema.Required,
+				ExtraFields: extras,
 			}
 		}
 
 		result[i] = anthropic.ToolUnionParam{OfTool: t}
 	}
 
-	return result
+	return result, nil
+}
+
+// schemaExtras returns keywords of the tool input schema other than type, properties and required (e.g. $defs or
+// additionalProperties), so the complete schema is sent to the model.
+func schemaExtras(tool agent.Tool) (map[string]any, error) {
+	data, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("tool %q input schema can not be serialized: %w", tool.Name, err)
+	}
+
+	extras := map[string]any{}
+	if err := json.Unmarshal(data, &extras); err != nil {
+		return nil, fmt.Errorf("tool %q input schema can not be serialized: %w", tool.Name, err)
+	}
+
+	delete(extras, "type")
+	delete(extras, "properties")
+	delete(extras, "required")
+
+	if len(extras) == 0 {
+		return nil, nil
+	}
+
+	return extras, nil
 }
 
 // mapFinishReason converts Anthropic's stop reason to the universal FinishReason type.
@@ -609,13 +778,15 @@ func mapFinishReason(reason anthropic.StopReason) agent.FinishReason {
 		return agent.FinishReasonToolCalls
 	case "stop_sequence":
 		return agent.FinishReasonStop
+	case "refusal":
+		return agent.FinishReasonContentFilter
 	default:
 		return agent.FinishReasonStop
 	}
 }
 
-func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNewParams {
-	params := anthropic.BetaMessageNewParams{Model: anthropic.Model(req.Model), MaxTokens: 8192}
+func toBetaAnthropicRequest(req agent.CompletionRequest) (anthropic.BetaMessageNewParams, error) {
+	params := anthropic.BetaMessageNewParams{Model: anthropic.Model(req.Model), MaxTokens: defaultMaxTokens(req.Model)}
 
 	if req.MaxTokens != nil {
 		params.MaxTokens = *req.MaxTokens
@@ -625,7 +796,8 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 		params.Temperature = param.NewOpt(float64(*req.Temperature))
 	}
 
-	if req.TopP != nil {
+	// top_p of 1 is the default, it's not sent because recent models do not accept both temperature and top_p
+	if req.TopP != nil && *req.TopP != 1 {
 		params.TopP = param.NewOpt(float64(*req.TopP))
 	}
 
@@ -633,6 +805,8 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 		params.TopK = param.NewOpt(int64(*req.TopK))
 	}
 
+	// Note: Anthropic does not support log probabilities, req.LogProbs and req.TopLogProbs are ignored
+
 	if req.UseCache != nil && *req.UseCache {
 		params.CacheControl = anthropic.NewBetaCacheControlEphemeralParam()
 	}
@@ -665,7 +839,7 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 		}
 	}
 
-	for _, msg := range req.Messages {
+	for i, msg := range req.Messages {
 		switch m := msg.(type) {
 		case agent.SystemMessage:
 			params.System = append(params.System, anthropic.BetaTextBlockParam{
@@ -673,6 +847,12 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 				Text: m.Content,
 			})
 
+		case agent.DeveloperMessage:
+			params.System = append(params.System, anthropic.BetaTextBlockParam{
+				Type: "text",
+				Text: m.Content,
+			})
+
 		case agent.UserMessage:
 			params.Messages = append(params.Messages, anthropic.BetaMessageParam{
 				Role:    "user",
@@ -711,13 +891,17 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 						},
 					}
 				case agent.MessageBlockTypeToolResult:
-					content[i] = anthropic.BetaContentBlockParamUnion{
-						OfWebSearchToolResult: &anthropic.BetaWebSearchToolResultBlockParam{
-							Type:      "web_search_tool_result",
-							ToolUseID: block.ToolResult.CallID,
-							// TODO: Parse and set Content properly when SDK supports it
-						},
+					result := &anthropic.BetaWebSearchToolResultBlockParam{
+						Type:      "web_search_tool_result",
+						ToolUseID: block.ToolResult.CallID,
+					}
+
+					// results of server tools are kept as raw JSON returned by the API
+					if raw, ok := block.ToolResult.Result.(string); ok {
+						_ = json.Unmarshal([]byte(raw), &result.Content)
 					}
+
+					content[i] = anthropic.BetaContentBlockParamUnion{OfWebSearchToolResult: result}
 				}
 			}
 
@@ -732,12 +916,7 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 				Content: []anthropic.BetaContentBlockParamUnion{{
 					OfToolResult: &anthropic.BetaToolResultBlockParam{
 						ToolUseID: m.CallID,
-						Content: []anthropic.BetaToolResultBlockParamContentUnion{{
-							OfText: &anthropic.BetaTextBlockParam{
-								Type: "text",
-								Text: m.String(),
-							},
-						}},
+						Content:   betaToolResultContent(m),
 					},
 				}},
 			})
@@ -759,10 +938,28 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 				}},
 			})
 		}
+
+		if i == req.CachedPrefix-1 {
+			betaCachePrefix(&params, msg)
+		}
+	}
+
+	if req.ResponseFormat != nil {
+		instruction, err := formatInstruction(*req.ResponseFormat)
+		if err != nil {
+			return anthropic.BetaMessageNewParams{}, err
+		}
+
+		params.System = append(params.System, anthropic.BetaTextBlockParam{Type: "text", Text: instruction})
 	}
 
 	if len(req.Tools) > 0 {
-		params.Tools = toBetaAnthropicTools(req.Tools)
+		tools, err := toBetaAnthropicTools(req.Tools)
+		if err != nil {
+			return anthropic.BetaMessageNewParams{}, err
+		}
+
+		params.Tools = tools
 
 		switch req.ToolChoice {
 		case agent.ToolChoiceAuto:
@@ -783,11 +980,19 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 
 	if req.Reasoning != nil {
 		if req.Reasoning.Enabled {
-			budget := int64(1024)
+			budget := effortBudget(req.Reasoning.Effort)
 			if req.Reasoning.Budget > 0 {
 				budget = int64(req.Reasoning.Budget)
 			}
 
+			// max tokens include thinking tokens, leave room for the reply if max tokens are not set explicitly
+			if req.MaxTokens == nil && params.MaxTokens <= budget {
+				params.MaxTokens = budget + defaultMaxTokens(req.Model)
+				if limit, ok := agent.ModelMaxTokens(req.Model); ok && params.MaxTokens > limit {
+					params.MaxTokens = limit
+				}
+			}
+
 			params.Thinking = anthropic.BetaThinkingConfigParamOfEnabled(budget)
 		} else {
 			disabled := anthropic.NewBetaThinkingConfigDisabledParam()
@@ -795,7 +1000,7 @@ func toBetaAnthropicRequest(req agent.CompletionRequest) anthropic.BetaMessageNe
 		}
 	}
 
-	return params
+	return params, nil
 }
 
 func fromBetaAnthropicResponse(ctx context.Context, resp *anthropic.BetaMessage) *agent.CompletionResponse {
@@ -821,10 +1026,13 @@ func fromBetaAnthropicResponse(ctx context.Context, resp *anthropic.BetaMessage)
 			ar.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeToolCall, ToolCall: &agent.ToolCall{ID: b.ID, Name: b.Name, Arguments: string(b.Input)}}
 		case "server_tool_use":
 			ar.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeServerToolCall, ToolCall: &agent.ToolCall{ID: b.ID, Name: b.Name, Arguments: string(b.Input)}}
-		case "web_search_tool_result":
-			ar.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeToolResult, ToolResult: &agent.ToolResult{CallID: b.ToolUseID, Result: fmt.Sprintf("%v", b.Content)}}
-		case "text_editor_code_execution_tool_result", "bash_code_execution_tool_result":
-			ar.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeToolResult, ToolResult: &agent.ToolResult{CallID: b.ToolUseID, Result: fmt.Sprintf("%v", b.Content)}}
+		case "web_search_tool_result", "text_editor_code_execution_tool_result", "bash_code_execution_tool_result":
+			result := b.JSON.Content.Raw()
+			if b.Content.Type == "text_editor_code_execution_view_result" {
+				result = b.Content.Content.OfString
+			}
+
+			ar.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeToolResult, ToolResult: &agent.ToolResult{CallID: b.ToolUseID, Result: result}}
 		default:
 			slog.WarnContext(ctx, "Unknown content block type", "channel", "llm", "type", b.Type)
 		}
@@ -833,7 +1041,7 @@ func fromBetaAnthropicResponse(ctx context.Context, resp *anthropic.BetaMessage)
 	return ar
 }
 
-func toBetaAnthropicTools(tools []agent.Tool) []anthropic.BetaToolUnionParam {
+func toBetaAnthropicTools(tools []agent.Tool) ([]anthropic.BetaToolUnionParam, error) {
 	result := make([]anthropic.BetaToolUnionParam, len(tools))
 
 	for i, tool := range tools {
@@ -874,19 +1082,25 @@ func toBetaAnthropicTools(tools []agent.Tool) []anthropic.BetaToolUnionParam {
 
 		if tool.InputSchema != nil && tool.InputSchema.Type != "" {
 			if tool.InputSchema.Type != "object" {
-				panic(fmt.Errorf("tool %q input schema must be object", tool.Name))
+				return nil, fmt.Errorf("tool %q input schema must be object", tool.Name)
+			}
+
+			extras, err := schemaExtras(tool)
+			if err != nil {
+				return nil, err
 			}
 
 			t.InputSchema = anthropic.BetaToolInputSchemaParam{
-				Properties: tool.InputSchema.Properties,
-				Required:   tool.InputSchema.Required,
+				Properties:  tool.InputSchema.Properties,
+				Required:    tool.InputSchema.Required,
+				ExtraFields: extras,
 			}
 		}
 
 		result[i] = anthropic.BetaToolUnionParam{OfTool: t}
 	}
 
-	return result
+	return result, nil
 }
 
 func mapBetaFinishReason(reason anthropic.BetaStopReason) agent.FinishReason {
@@ -899,7 +1113,45 @@ func mapBetaFinishReason(reason anthropic.BetaStopReason) agent.FinishReason {
 		return agent.FinishReasonToolCalls
 	case "stop_sequence":
 		return agent.FinishReasonStop
+	case "refusal":
+		return agent.FinishReasonContentFilter
 	default:
 		return agent.FinishReasonStop
 	}
 }
+
+// effortBudget returns the budget of thinking tokens matching reasoning effort, Anthropic has no notion of effort.
+func effortBudget(effort string) int64 {
+	switch effort {
+	case "medium":
+		return 4096
+	case "high":
+		return 16384
+	default:
+		return 1024
+	}
+}
+
+// requestOptions returns per-request options, the SDK reuses them when it retries the request.
+func requestOptions(req agent.CompletionRequest) []option.RequestOption {
+	var opts []option.RequestOption
+	if req.IdempotencyKey != "" {
+		opts = append(opts, option.WithHeader("Idempotency-Key", req.IdempotencyKey))
+	}
+
+	for key, value := range req.Extra {
+		opts = append(opts, option.WithJSONSet(key, value))
+	}
+
+	return opts
+}
+
+// defaultMaxTokens returns max tokens used when request does not specify them: 8192 or the limit of the model if it's
+// lower. The model limit is not used as is, because large values require streaming.
+func defaultMaxTokens(model string) int64 {
+	if limit, ok := agent.ModelMaxTokens(model); ok && limit < 8192 {
+		return limit
+	}
+
+	return 8192
+}
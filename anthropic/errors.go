@@ -0,0 +1,33 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/eolymp/go-agent"
+)
+
+// wrapError converts Anthropic SDK error to agent.APIError, other errors are returned as is.
+func wrapError(err error) error {
+	var e *anthropic.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	var body struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	_ = json.Unmarshal([]byte(e.RawJSON()), &body)
+
+	message := body.Error.Message
+	if message == "" {
+		message = e.Error()
+	}
+
+	return agent.NewAPIError(e.StatusCode, body.Error.Type, message, err)
+}
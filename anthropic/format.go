@@ -0,0 +1,93 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/eolymp/go-agent"
+)
+
+// Anthropic has no native JSON mode, so response format is enforced with a system instruction. Additionally, if the
+// schema is an object and a non-streaming request has no tools, the model is forced to call a single tool taking the
+// schema as its input, the input of the call is returned as the text of the reply.
+//
+// Streaming requests and requests sent to the beta API (extended thinking, betas, containers or server tools) rely on
+// the instruction only: forced tool choice is not compatible with extended thinking, and streamed chunks of the tool
+// call can not be turned into text chunks. The agent validates the reply and asks the model to fix it in both cases.
+
+// formatInstruction returns the system instruction asking the model to respond with JSON matching the format.
+func formatInstruction(format agent.ResponseFormat) (string, error) {
+	text := "Respond only with a JSON value, without any other text or markdown formatting."
+	if format.Schema == nil {
+		return text, nil
+	}
+
+	schema, err := json.Marshal(format.Schema)
+	if err != nil {
+		return "", fmt.Errorf("response schema can not be serialized: %w", err)
+	}
+
+	return text + " The JSON value must match this JSON schema:\n" + string(schema), nil
+}
+
+// formatTool returns the tool used to enforce the response format, it returns false if the request can not be
+// completed with a forced tool call: it has tools of its own, uses extended thinking (forced tool choice is not
+// compatible with it) or the schema does not describe an object.
+func formatTool(req agent.CompletionRequest) (agent.Tool, bool) {
+	format := req.ResponseFormat
+	if format == nil || format.Schema == nil || format.Schema.Type != "object" {
+		return agent.Tool{}, false
+	}
+
+	if len(req.Tools) > 0 || req.Reasoning != nil {
+		return agent.Tool{}, false
+	}
+
+	name := format.Name
+	if name == "" {
+		name = "response"
+	}
+
+	return agent.Tool{
+		Name:        name,
+		Description: "Respond to the user, input of the tool is the response.",
+		InputSchema: format.Schema,
+	}, true
+}
+
+// toFormatRequest converts the request to Anthropic params forcing the call of the format tool.
+func toFormatRequest(req agent.CompletionRequest, tool agent.Tool) (anthropic.MessageNewParams, error) {
+	params, err := toAnthropicRequest(req)
+	if err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	params.Tools, err = toAnthropicTools([]agent.Tool{tool})
+	if err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	params.ToolChoice = anthropic.ToolChoiceUnionParam{
+		OfTool: &anthropic.ToolChoiceToolParam{Name: tool.Name},
+	}
+
+	return params, nil
+}
+
+// fromFormatResponse replaces the call of the format tool with a text block containing its input.
+func fromFormatResponse(resp *agent.CompletionResponse, tool agent.Tool) *agent.CompletionResponse {
+	for i, block := range resp.Content {
+		if block.Type != agent.MessageBlockTypeToolCall || block.ToolCall == nil || block.ToolCall.Name != tool.Name {
+			continue
+		}
+
+		resp.Content[i] = agent.MessageBlock{Type: agent.MessageBlockTypeText, Text: block.ToolCall.Arguments}
+
+		if resp.FinishReason == agent.FinishReasonToolCalls {
+			resp.FinishReason = agent.FinishReasonStop
+		}
+	}
+
+	return resp
+}
@@ -0,0 +1,10 @@
+package anthropic
+
+import "github.com/eolymp/go-agent"
+
+// NewTokenCounter creates an approximate token counter for Claude models.
+// Anthropic does not publish its tokenizer, so counting is based on the average of 3.5 characters per token.
+// Use it for context window trimming and cost estimation, not for exact billing.
+func NewTokenCounter() *agent.ApproximateTokenCounter {
+	return &agent.ApproximateTokenCounter{CharsPerToken: 3.5, MessageOverhead: 5}
+}
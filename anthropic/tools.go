@@ -36,15 +36,23 @@ func WithWebSearchTool() agent.Option {
 }
 
 // WithToolSearchRegex adds Anthropic's regex-based tool search tool to the agent (beta only).
-// This tool is automatically handled by Anthropic's backend.
+// This tool is automatically handled by Anthropic's backend and requires the advanced-tool-use-2025-11-20 beta flag.
+// The beta flag is automatically added when using this tool.
 func WithToolSearchRegex() agent.Option {
-	return agent.WithBuiltinTool("tool_search_tool_regex", "tool_search_tool_regex_20251119")
+	return agent.WithOptions(
+		agent.WithBuiltinTool("tool_search_tool_regex", "tool_search_tool_regex_20251119"),
+		agent.WithBetas("advanced-tool-use-2025-11-20"),
+	)
 }
 
 // WithToolSearchBM25 adds Anthropic's BM25-based tool search tool to the agent (beta only).
-// This tool is automatically handled by Anthropic's backend.
+// This tool is automatically handled by Anthropic's backend and requires the advanced-tool-use-2025-11-20 beta flag.
+// The beta flag is automatically added when using this tool.
 func WithToolSearchBM25() agent.Option {
-	return agent.WithBuiltinTool("tool_search_tool_bm25", "tool_search_tool_bm25_20251119")
+	return agent.WithOptions(
+		agent.WithBuiltinTool("tool_search_tool_bm25", "tool_search_tool_bm25_20251119"),
+		agent.WithBetas("advanced-tool-use-2025-11-20"),
+	)
 }
 
 // WithSkills adds skills to the agent container with the required skills beta flag.
@@ -0,0 +1,36 @@
+package agent
+
+// AttachmentType defines kind of non-text content attached to a tool result.
+type AttachmentType string
+
+const (
+	AttachmentTypeImage    AttachmentType = "image"
+	AttachmentTypeDocument AttachmentType = "document"
+)
+
+// Attachment is a binary content (image or document) produced by a tool, which is passed to the model along with
+// the tool result, e.g. a chart or a screenshot for a vision model.
+type Attachment struct {
+	Type      AttachmentType `json:"type"`
+	MediaType string         `json:"media_type"`     // MIME type, e.g. image/png or application/pdf
+	Name      string         `json:"name,omitempty"` // file name, used by providers which require it for documents
+	Data      []byte         `json:"data"`
+}
+
+func NewImageAttachment(mediaType string, data []byte) Attachment {
+	return Attachment{Type: AttachmentTypeImage, MediaType: mediaType, Data: data}
+}
+
+func NewDocumentAttachment(name, mediaType string, data []byte) Attachment {
+	return Attachment{Type: AttachmentTypeDocument, MediaType: mediaType, Name: name, Data: data}
+}
+
+// ToolOutput can be returned by a tool to attach images or documents to its result.
+//
+// Anthropic models receive attachments as a part of the tool result. OpenAI does not support non-text tool results,
+// so attachments are sent in a user message following tool results. OpenAI-compatible APIs of other providers (Mistral,
+// Groq, Gemini) and Cohere do not receive attachments, the tool result notes that they are not supported.
+type ToolOutput struct {
+	Result      any
+	Attachments []Attachment
+}
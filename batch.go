@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// BatchResult is the outcome of a single input processed by RunBatch.
+type BatchResult struct {
+	Input  string
+	Reply  AssistantMessage
+	Memory Memory
+	Error  error
+}
+
+// RunBatch runs the agent once per input, each time with a fresh memory seeded with the input as a user message.
+// At most concurrency runs are executed at the same time (no limit if concurrency is zero or negative).
+//
+// Results are returned in the order of inputs. Failure of a single run is reported in its result and doesn't stop
+// the batch, error is returned only if the context is cancelled before all inputs are processed.
+func (a Agent) RunBatch(ctx context.Context, inputs []string, concurrency int, opts ...Option) ([]BatchResult, error) {
+	results := make([]BatchResult, len(inputs))
+
+	eg := errgroup.Group{}
+	if concurrency > 0 {
+		eg.SetLimit(concurrency)
+	}
+
+	for i, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+
+		eg.Go(func() error {
+			memory := NewStaticMemory()
+			results[i] = BatchResult{Input: input, Memory: memory}
+
+			if err := memory.Append(ctx, NewUserMessage(input)); err != nil {
+				results[i].Error = err
+				return nil
+			}
+
+			// copy options, so concurrent runs don't share the backing array
+			results[i].Reply, results[i].Error = a.Run(ctx, append(append([]Option{}, opts...), WithMemory(memory))...)
+
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	return results, ctx.Err()
+}
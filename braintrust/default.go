@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/braintrustdata/braintrust-go"
+	"github.com/eolymp/go-agent"
 )
 
 var prompter *Prompter
@@ -24,6 +25,11 @@ func SetDefaultPrompter(t *Prompter) {
 	prompter = t
 }
 
-func Load(ctx context.Context, slug string) (*Prompt, error) {
-	return DefaultPrompter().Load(ctx, slug)
+// RegisterTool binds implementation of a function tool declared in prompt metadata using the default prompter.
+func RegisterTool(name string, tool agent.Option) {
+	DefaultPrompter().RegisterTool(name, tool)
+}
+
+func Load(ctx context.Context, slug string, opts ...LoadOption) (*Prompt, error) {
+	return DefaultPrompter().Load(ctx, slug, opts...)
 }
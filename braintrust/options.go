@@ -2,17 +2,25 @@ package braintrust
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/eolymp/go-agent"
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
-func WithPrompt(slug string) agent.Option {
-	return WithPrompter(DefaultPrompter(), slug)
+func WithPrompt(slug string, opts ...LoadOption) agent.Option {
+	return WithPrompter(DefaultPrompter(), slug, opts...)
 }
 
-func WithPrompter(prompter *Prompter, slug string) agent.Option {
+// WithPinnedPrompt loads a specific version of the prompt, see WithVersion.
+func WithPinnedPrompt(slug, version string) agent.Option {
+	return WithPrompt(slug, WithVersion(version))
+}
+
+func WithPrompter(prompter *Prompter, slug string, opts ...LoadOption) agent.Option {
 	return agent.WithOptionLoader(func(ctx context.Context, a *agent.Agent) error {
-		prompt, err := prompter.Load(ctx, slug)
+		prompt, err := prompter.Load(ctx, slug, opts...)
 		if err != nil {
 			return err
 		}
@@ -30,6 +38,10 @@ func WithPrompter(prompter *Prompter, slug string) agent.Option {
 			}
 		}
 
+		if len(prompt.Values) > 0 {
+			opts = append(opts, agent.WithDefaultValues(prompt.Values))
+		}
+
 		if prompt.Model != "" {
 			opts = append(opts, agent.WithModel(prompt.Model))
 		}
@@ -71,6 +83,28 @@ func WithPrompter(prompter *Prompter, slug string) agent.Option {
 			opts = append(opts, agent.WithReasoning(thinking))
 		}
 
+		if prompt.ResponseFormat != nil {
+			switch {
+			case prompt.ResponseFormat.Type == "json_schema" && prompt.ResponseFormat.Schema != nil && len(prompt.ResponseFormat.Schema.Schema) > 0:
+				schema := &jsonschema.Schema{}
+				if err := json.Unmarshal(prompt.ResponseFormat.Schema.Schema, schema); err != nil {
+					return fmt.Errorf("prompt %q has invalid response schema: %w", slug, err)
+				}
+
+				if _, err := schema.Resolve(nil); err != nil {
+					return fmt.Errorf("prompt %q has invalid response schema: %w", slug, err)
+				}
+
+				if schema.Title == "" {
+					schema.Title = prompt.ResponseFormat.Schema.Name
+				}
+
+				opts = append(opts, agent.WithJSONSchema(schema))
+			case prompt.ResponseFormat.Type == "json_object" || prompt.ResponseFormat.Type == "json_schema":
+				opts = append(opts, agent.WithStructuredOutput())
+			}
+		}
+
 		if prompt.Metadata != nil {
 			if len(prompt.Metadata.AutoApproveTools) > 0 {
 				opts = append(opts, agent.WithAutoApproveTools(prompt.Metadata.AutoApproveTools...))
@@ -97,7 +131,18 @@ func WithPrompter(prompter *Prompter, slug string) agent.Option {
 
 			if len(prompt.Metadata.Tools) > 0 {
 				for _, tool := range prompt.Metadata.Tools {
-					opts = append(opts, agent.WithBuiltinTool(tool.Name, tool.Type))
+					// built-in tools are executed by the provider
+					if tool.Type != "" && tool.Type != "function" {
+						opts = append(opts, agent.WithBuiltinTool(tool.Name, tool.Type))
+						continue
+					}
+
+					opt, ok := prompter.tool(tool.Name)
+					if !ok {
+						return fmt.Errorf("prompt %q declares tool %q, but it has no registered implementation", slug, tool.Name)
+					}
+
+					opts = append(opts, opt)
 				}
 			}
 		}
@@ -25,6 +25,7 @@ type Prompt struct {
 	ToolFunction   *string
 	ResponseFormat *Response
 	Reasoning      *Reasoning
+	Values         map[string]any // default values of template variables, defined with "values" key of metadata
 	Metadata       *Metadata
 }
 
@@ -34,10 +35,11 @@ type Message struct {
 }
 
 type Metadata struct {
-	AutoApproveTools []string `json:"auto_approve_tools,omitempty"`
-	Betas            []string `json:"betas,omitempty"`
-	Skills           []Skill  `json:"skills,omitempty"`
-	Tools            []Tool   `json:"tools,omitempty"`
+	AutoApproveTools []string       `json:"auto_approve_tools,omitempty"`
+	Betas            []string       `json:"betas,omitempty"`
+	Skills           []Skill        `json:"skills,omitempty"`
+	Tools            []Tool         `json:"tools,omitempty"`
+	Values           map[string]any `json:"values,omitempty"`
 }
 
 type Skill struct {
@@ -48,7 +50,7 @@ type Skill struct {
 
 type Tool struct {
 	Name string `json:"name,omitempty"`
-	Type string `json:"type,omitempty"`
+	Type string `json:"type,omitempty"` // type of built-in tool, empty or "function" for tools implemented with RegisterTool
 }
 
 type Response struct {
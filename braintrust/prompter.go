@@ -4,27 +4,65 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/braintrustdata/braintrust-go"
 	"github.com/braintrustdata/braintrust-go/packages/param"
+	"github.com/eolymp/go-agent"
 )
 
 type Prompter struct {
 	cli     braintrust.Client
 	project string
+	lock    sync.Mutex
+	tools   map[string]agent.Option
 }
 
 func NewPrompter(cli braintrust.Client, project string) *Prompter {
-	return &Prompter{cli: cli, project: project}
+	return &Prompter{cli: cli, project: project, tools: map[string]agent.Option{}}
 }
 
-func (p *Prompter) Load(ctx context.Context, slug string) (*Prompt, error) {
-	prompts, err := p.cli.Prompts.List(ctx, braintrust.PromptListParams{
+// RegisterTool binds implementation of a function tool declared in prompt metadata, the option is applied when a
+// prompt declaring the tool is loaded. The option is expected to add the tool, e.g. agent.WithInlineTool.
+func (p *Prompter) RegisterTool(name string, tool agent.Option) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.tools[name] = tool
+}
+
+func (p *Prompter) tool(name string) (agent.Option, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	tool, ok := p.tools[name]
+	return tool, ok
+}
+
+type LoadOption func(*braintrust.PromptListParams)
+
+// WithVersion pins the prompt to a specific version (transaction id or version identifier), so changes made to the
+// prompt in Braintrust don't affect the application until the version is updated.
+func WithVersion(version string) LoadOption {
+	return func(params *braintrust.PromptListParams) {
+		params.Version = param.NewOpt(version)
+	}
+}
+
+// Load fetches the prompt by slug, the latest version is returned unless pinned with WithVersion.
+func (p *Prompter) Load(ctx context.Context, slug string, opts ...LoadOption) (*Prompt, error) {
+	query := braintrust.PromptListParams{
 		Limit:     param.NewOpt[int64](1),
 		ProjectID: param.NewOpt(p.project),
 		Slug:      param.NewOpt(slug),
-	})
+	}
+
+	for _, opt := range opts {
+		opt(&query)
+	}
+
+	prompts, err := p.cli.Prompts.List(ctx, query)
 
 	if err != nil {
 		return nil, err
@@ -122,6 +160,7 @@ func (p *Prompter) Load(ctx context.Context, slug string) (*Prompt, error) {
 			var metadata Metadata
 			if err := json.Unmarshal(bytes, &metadata); err == nil {
 				result.Metadata = &metadata
+				result.Values = metadata.Values
 			}
 		}
 	}
@@ -0,0 +1,489 @@
+package cohere
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eolymp/go-agent"
+)
+
+// BaseURL is the endpoint of Cohere API.
+const BaseURL = "https://api.cohere.com/v1/"
+
+// Completer implements agent.ChatCompleter using Cohere Chat API.
+//
+// Cohere does not assign IDs to tool calls, so the completer generates them from the generation ID and matches tool
+// results back to the calls by these IDs when building the next request.
+type Completer struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+type Option func(*Completer)
+
+// WithBaseURL overrides the API endpoint, e.g. to use a private deployment.
+func WithBaseURL(url string) Option {
+	return func(c *Completer) {
+		c.baseURL = strings.TrimSuffix(url, "/") + "/"
+	}
+}
+
+// WithHTTPClient replaces default HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Completer) {
+		c.client = client
+	}
+}
+
+// New creates a new Cohere chat completer with the given API key.
+func New(apiKey string, opts ...Option) *Completer {
+	c := &Completer{apiKey: apiKey, baseURL: BaseURL, client: http.DefaultClient}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Complete implements agent.ChatCompleter.
+func (c *Completer) Complete(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
+	params, err := toCohereRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.send(ctx, params, req.IdempotencyKey, req.Extra)
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+
+	if req.StreamCallback != nil {
+		return c.stream(ctx, req, body)
+	}
+
+	var resp chatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("unable to decode cohere response: %w", err)
+	}
+
+	return fromCohereResponse(req.Model, resp), nil
+}
+
+// Ping implements agent.Pinger by listing available models.
+func (c *Completer) Ping(ctx context.Context) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"models?page_size=1", nil)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Authorization", "Bearer "+c.apiKey)
+	r.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+func (c *Completer) send(ctx context.Context, params chatRequest, key string, extra map[string]any) (io.ReadCloser, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(extra) > 0 {
+		body := map[string]any{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, err
+		}
+
+		for k, v := range extra {
+			body[k] = v
+		}
+
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"chat", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Authorization", "Bearer "+c.apiKey)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+
+	if key != "" {
+		r.Header.Set("Idempotency-Key", key)
+	}
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// stream reads newline delimited stream events and forwards them to the stream callback.
+func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest, body io.Reader) (*agent.CompletionResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("unable to decode cohere stream event: %w", err)
+		}
+
+		switch event.EventType {
+		case "text-generation":
+			if err := req.StreamCallback(ctx, agent.Chunk{Type: agent.StreamChunkTypeText, Text: event.Text}); err != nil {
+				return nil, err
+			}
+
+		case "stream-end":
+			if event.Response == nil {
+				return nil, fmt.Errorf("cohere stream ended without response")
+			}
+
+			resp := fromCohereResponse(req.Model, *event.Response)
+
+			for index, block := range resp.Content {
+				if block.Type != agent.MessageBlockTypeToolCall {
+					continue
+				}
+
+				chunks := []agent.Chunk{
+					{Type: agent.StreamChunkTypeToolCallStart, Index: index, Call: &agent.ToolCall{ID: block.ToolCall.ID, Name: block.ToolCall.Name}},
+					{Type: agent.StreamChunkTypeToolCallDelta, Index: index, Call: block.ToolCall},
+				}
+
+				for _, chunk := range chunks {
+					if err := req.StreamCallback(ctx, chunk); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			if err := req.StreamCallback(ctx, agent.Chunk{Type: agent.StreamChunkTypeUsage, Usage: &resp.Usage}); err != nil {
+				return nil, err
+			}
+
+			if err := req.StreamCallback(ctx, agent.Chunk{Type: agent.StreamChunkTypeFinish, FinishReason: resp.FinishReason, Usage: &resp.Usage}); err != nil {
+				return nil, err
+			}
+
+			return resp, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("cohere stream ended unexpectedly")
+}
+
+type chatRequest struct {
+	Model       string        `json:"model,omitempty"`
+	Message     string        `json:"message"`
+	Preamble    string        `json:"preamble,omitempty"`
+	ChatHistory []chatMessage `json:"chat_history,omitempty"`
+	Tools       []tool        `json:"tools,omitempty"`
+	ToolResults []toolResult  `json:"tool_results,omitempty"`
+	MaxTokens   *int64        `json:"max_tokens,omitempty"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	P           *float32      `json:"p,omitempty"`
+	K           *int32        `json:"k,omitempty"`
+	Seed        *int64        `json:"seed,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type chatMessage struct {
+	Role        string       `json:"role"`
+	Message     string       `json:"message,omitempty"`
+	ToolCalls   []toolCall   `json:"tool_calls,omitempty"`
+	ToolResults []toolResult `json:"tool_results,omitempty"`
+}
+
+type toolCall struct {
+	Name       string         `json:"name"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+type toolResult struct {
+	Call    toolCall         `json:"call"`
+	Outputs []map[string]any `json:"outputs"`
+}
+
+type tool struct {
+	Name                 string                         `json:"name"`
+	Description          string                         `json:"description"`
+	ParameterDefinitions map[string]parameterDefinition `json:"parameter_definitions,omitempty"`
+}
+
+type parameterDefinition struct {
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type chatResponse struct {
+	Text         string     `json:"text"`
+	GenerationID string     `json:"generation_id"`
+	FinishReason string     `json:"finish_reason"`
+	ToolCalls    []toolCall `json:"tool_calls"`
+	Meta         struct {
+		Tokens struct {
+			InputTokens  float64 `json:"input_tokens"`
+			OutputTokens float64 `json:"output_tokens"`
+		} `json:"tokens"`
+	} `json:"meta"`
+}
+
+type streamEvent struct {
+	EventType string        `json:"event_type"`
+	Text      string        `json:"text"`
+	Response  *chatResponse `json:"response"`
+}
+
+// toCohereRequest converts a universal CompletionRequest to Cohere chat request.
+//
+// Cohere expects system messages as preamble, the last user message (or results of the last tool calls) separately
+// from the rest of the conversation, which is passed as chat history.
+func toCohereRequest(req agent.CompletionRequest) (chatRequest, error) {
+	params := chatRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		P:           req.TopP,
+		K:           req.TopK,
+		Seed:        req.Seed,
+		Stream:      req.StreamCallback != nil,
+		Tools:       toCohereTools(req.Tools),
+	}
+
+	// tool calls by ID, to match results with calls
+	calls := map[string]toolCall{}
+
+	var preamble []string
+	var history []chatMessage
+
+	for _, msg := range req.Messages {
+		switch m := msg.(type) {
+		case agent.SystemMessage:
+			preamble = append(preamble, m.Content)
+		case agent.DeveloperMessage:
+			preamble = append(preamble, m.Content)
+		case agent.UserMessage:
+			history = append(history, chatMessage{Role: "USER", Message: m.Content})
+		case agent.AssistantMessage:
+			cm := chatMessage{Role: "CHATBOT", Message: m.Text()}
+
+			for _, block := range m.Content {
+				if block.Type != agent.MessageBlockTypeToolCall || block.ToolCall == nil {
+					continue
+				}
+
+				call, err := toCohereToolCall(*block.ToolCall)
+				if err != nil {
+					return params, err
+				}
+
+				calls[block.ToolCall.ID] = call
+				cm.ToolCalls = append(cm.ToolCalls, call)
+			}
+
+			history = append(history, cm)
+		case agent.ToolResult:
+			output := map[string]any{"result": m.String()}
+
+			// Cohere does not accept images or documents in tool results, the model is told about them
+			if len(m.Attachments) > 0 {
+				var attachments []string
+				for _, a := range m.Attachments {
+					attachments = append(attachments, fmt.Sprintf("[attachment %q of type %s is not supported]", a.Name, a.MediaType))
+				}
+
+				output["attachments"] = attachments
+			}
+
+			history = appendToolResult(history, toolResult{Call: calls[m.CallID], Outputs: []map[string]any{output}})
+		case agent.ToolError:
+			text := m.Error
+			if m.Content != "" {
+				text = m.Content
+			}
+
+			history = appendToolResult(history, toolResult{Call: calls[m.CallID], Outputs: []map[string]any{{"error": text}}})
+		default:
+			return params, fmt.Errorf("message type %T is not supported by cohere completer", msg)
+		}
+	}
+
+	params.Preamble = strings.Join(preamble, "\n\n")
+
+	// the last user message or tool results are sent separately from the history
+	if n := len(history); n > 0 {
+		switch last := history[n-1]; last.Role {
+		case "USER":
+			params.Message = last.Message
+			history = history[:n-1]
+		case "TOOL":
+			params.ToolResults = last.ToolResults
+			history = history[:n-1]
+		}
+	}
+
+	params.ChatHistory = history
+
+	return params, nil
+}
+
+// appendToolResult adds tool result to the history, consecutive results are grouped into a single message.
+func appendToolResult(history []chatMessage, result toolResult) []chatMessage {
+	if n := len(history); n > 0 && history[n-1].Role == "TOOL" {
+		history[n-1].ToolResults = append(history[n-1].ToolResults, result)
+		return history
+	}
+
+	return append(history, chatMessage{Role: "TOOL", ToolResults: []toolResult{result}})
+}
+
+func toCohereToolCall(call agent.ToolCall) (toolCall, error) {
+	params := map[string]any{}
+	if call.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Arguments), &params); err != nil {
+			return toolCall{}, fmt.Errorf("tool call %q has invalid arguments: %w", call.ID, err)
+		}
+	}
+
+	return toolCall{Name: call.Name, Parameters: params}, nil
+}
+
+// toCohereTools converts internal tools to Cohere tools, Cohere describes only top-level parameters of the tool.
+func toCohereTools(tools []agent.Tool) []tool {
+	var result []tool
+
+	for _, t := range tools {
+		ct := tool{Name: t.Name, Description: t.Description}
+
+		if t.InputSchema != nil && len(t.InputSchema.Properties) > 0 {
+			required := map[string]bool{}
+			for _, name := range t.InputSchema.Required {
+				required[name] = true
+			}
+
+			ct.ParameterDefinitions = map[string]parameterDefinition{}
+			for name, prop := range t.InputSchema.Properties {
+				ct.ParameterDefinitions[name] = parameterDefinition{
+					Description: prop.Description,
+					Type:        toCohereType(prop.Type),
+					Required:    required[name],
+				}
+			}
+		}
+
+		result = append(result, ct)
+	}
+
+	return result
+}
+
+// toCohereType converts JSON schema type to Python-like type used by Cohere.
+func toCohereType(t string) string {
+	switch t {
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "list"
+	case "object":
+		return "dict"
+	default:
+		return "str"
+	}
+}
+
+// fromCohereResponse converts Cohere chat response to a universal CompletionResponse.
+func fromCohereResponse(model string, resp chatResponse) *agent.CompletionResponse {
+	result := &agent.CompletionResponse{
+		Model:        model,
+		FinishReason: mapFinishReason(resp.FinishReason),
+		Usage: agent.CompletionUsage{
+			PromptTokens:     int(resp.Meta.Tokens.InputTokens),
+			CompletionTokens: int(resp.Meta.Tokens.OutputTokens),
+			TotalTokens:      int(resp.Meta.Tokens.InputTokens + resp.Meta.Tokens.OutputTokens),
+		},
+	}
+
+	if resp.Text != "" {
+		result.Content = append(result.Content, agent.MessageBlock{Type: agent.MessageBlockTypeText, Text: resp.Text})
+	}
+
+	for i, call := range resp.ToolCalls {
+		args, _ := json.Marshal(call.Parameters)
+
+		result.Content = append(result.Content, agent.MessageBlock{
+			Type: agent.MessageBlockTypeToolCall,
+			ToolCall: &agent.ToolCall{
+				ID:        fmt.Sprintf("%s_%d", resp.GenerationID, i),
+				Name:      call.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+
+	// Cohere finishes with COMPLETE when it requests tool calls
+	if len(resp.ToolCalls) > 0 && result.FinishReason == agent.FinishReasonStop {
+		result.FinishReason = agent.FinishReasonToolCalls
+	}
+
+	return result
+}
+
+// mapFinishReason converts Cohere's finish reason to the universal FinishReason type.
+func mapFinishReason(reason string) agent.FinishReason {
+	switch reason {
+	case "MAX_TOKENS", "ERROR_LIMIT":
+		return agent.FinishReasonLength
+	case "ERROR_TOXIC":
+		return agent.FinishReasonContentFilter
+	default:
+		return agent.FinishReasonStop
+	}
+}
@@ -0,0 +1,83 @@
+package cohere_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+	"github.com/eolymp/go-agent/cohere"
+)
+
+func TestCompleter_Errors(t *testing.T) {
+	tests := map[string]struct {
+		status    int
+		body      string
+		message   string
+		retryable bool
+	}{
+		"rate limit": {
+			status:    http.StatusTooManyRequests,
+			body:      `{"message":"too many requests"}`,
+			message:   "too many requests",
+			retryable: true,
+		},
+		"invalid request": {
+			status:  http.StatusBadRequest,
+			body:    `{"message":"invalid model"}`,
+			message: "invalid model",
+		},
+		"server error without body": {
+			status:    http.StatusServiceUnavailable,
+			message:   "503 Service Unavailable",
+			retryable: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			completer := cohere.New("key", cohere.WithBaseURL(srv.URL))
+
+			check := func(t *testing.T, err error) {
+				var e *agent.APIError
+				if !errors.As(err, &e) {
+					t.Fatalf("Expected agent.APIError, got %v", err)
+				}
+
+				if e.StatusCode != tc.status {
+					t.Errorf("Expected status %d, got %d", tc.status, e.StatusCode)
+				}
+
+				if e.Message != tc.message {
+					t.Errorf("Expected message %q, got %q", tc.message, e.Message)
+				}
+
+				if e.Retryable != tc.retryable {
+					t.Errorf("Expected retryable %v, got %v", tc.retryable, e.Retryable)
+				}
+			}
+
+			t.Run("complete", func(t *testing.T) {
+				_, err := completer.Complete(context.Background(), agent.CompletionRequest{
+					Model:    "command-r",
+					Messages: []agent.Message{agent.NewUserMessage("Hello")},
+				})
+
+				check(t, err)
+			})
+
+			t.Run("ping", func(t *testing.T) {
+				check(t, completer.Ping(context.Background()))
+			})
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package cohere
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/eolymp/go-agent"
+)
+
+// responseError converts unsuccessful Cohere response to agent.APIError, the body of the response is consumed.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+
+	raw, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(raw, &body); err != nil || body.Message == "" {
+		body.Message = string(raw)
+	}
+
+	if body.Message == "" {
+		body.Message = resp.Status
+	}
+
+	return agent.NewAPIError(resp.StatusCode, "", body.Message, nil)
+}
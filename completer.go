@@ -1,6 +1,11 @@
 package agent
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
 
 var defaultCompleter ChatCompleter
 
@@ -8,6 +13,13 @@ func SetChatCompleter(c ChatCompleter) {
 	defaultCompleter = c
 }
 
+var defaultModel string
+
+// SetDefaultModel sets the model used by agents which have no model set with WithModel option or loaded prompt.
+func SetDefaultModel(model string) {
+	defaultModel = model
+}
+
 // ChatCompleter defines the interface for chat completion operations.
 // This abstraction allows for different LLM providers (OpenAI, Anthropic, Google, etc.)
 type ChatCompleter interface {
@@ -15,6 +27,12 @@ type ChatCompleter interface {
 	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
 }
 
+// Pinger is implemented by completers which can verify connectivity and credentials of the provider without running
+// a completion, e.g. for readiness probes.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // ToolChoice represents how the model should use tools during completion.
 type ToolChoice int
 
@@ -71,6 +89,21 @@ func (f FinishReason) String() string {
 	}
 }
 
+func (f FinishReason) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *FinishReason) UnmarshalText(text []byte) error {
+	for r := FinishReasonStop; r <= FinishReasonContentFilter; r++ {
+		if r.String() == string(text) {
+			*f = r
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown finish reason %q", string(text))
+}
+
 type Container struct {
 	ID     string
 	Skills []Skill
@@ -88,6 +121,13 @@ type Reasoning struct {
 	Effort  string // "low", "medium", "high" (OpenAI specific)
 }
 
+// ResponseFormat requires the model to reply with JSON. Providers with native support use JSON mode (or structured
+// output if schema is given), others are instructed to respond with JSON only.
+type ResponseFormat struct {
+	Name   string             // name of the schema matching ^[a-zA-Z0-9_-]{1,64}$, some providers require it, "response" is used if empty
+	Schema *jsonschema.Schema // schema of the reply, if nil any JSON value is accepted
+}
+
 type CompletionRequest struct {
 	Model             string
 	Messages          []Message
@@ -97,11 +137,18 @@ type CompletionRequest struct {
 	MaxTokens         *int64
 	Temperature       *float32
 	TopP              *float32
-	TopK              *int32
+	TopK              *int32 // not supported by OpenAI, ignored there
+	Seed              *int64 // seed for sampling to get reproducible results (best effort), not supported by Anthropic
 	UseCache          *bool
+	CachedPrefix      int // number of leading messages which are the same in subsequent requests, Anthropic sets a cache breakpoint after them
 	Container         *Container
 	Betas             []string
 	Reasoning         *Reasoning
+	ResponseFormat    *ResponseFormat // require the reply to be a JSON value, see WithJSONSchema
+	LogProbs          bool            // return log probabilities of output tokens (OpenAI only, not supported by Anthropic)
+	TopLogProbs       int             // number of the most likely alternatives returned for each token, requires LogProbs
+	IdempotencyKey    string          // sent to providers supporting it to avoid duplicate generations when a request is retried
+	Extra             map[string]any  // provider specific parameters set as fields of request body, see WithRawCompletion
 	StreamCallback    func(ctx context.Context, chunk Chunk) error
 }
 
@@ -111,6 +158,14 @@ type CompletionResponse struct {
 	FinishReason FinishReason
 	Usage        CompletionUsage
 	Model        string
+	LogProbs     []TokenLogProb // log probabilities of output tokens, if requested and supported by the provider
+}
+
+// TokenLogProb is a log probability of an output token.
+type TokenLogProb struct {
+	Token   string         `json:"token"`
+	LogProb float64        `json:"logprob"`
+	Top     []TokenLogProb `json:"top,omitempty"` // the most likely alternatives of the token
 }
 
 // CompletionUsage represents token usage information for a completion.
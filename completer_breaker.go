@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerCompleter stops calling the underlying completer after a number of consecutive failures.
+//
+// Once the failure threshold is reached, the circuit opens and requests fail immediately with ErrCircuitOpen until
+// the cool-down elapses. Then the circuit becomes half-open: a single request is let through to test the provider,
+// if it succeeds the circuit closes, otherwise it opens again for another cool-down period.
+//
+// Cancelled requests (context cancellation or deadline) and non-retryable provider errors (e.g. invalid request) are
+// not counted as failures.
+type CircuitBreakerCompleter struct {
+	completer ChatCompleter
+	threshold int
+	cooldown  time.Duration
+	classify  func(err error) bool
+
+	lock     sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+type CircuitBreakerOption func(*CircuitBreakerCompleter)
+
+// BreakerThreshold sets the number of consecutive failures opening the circuit, default is 5.
+func BreakerThreshold(n int) CircuitBreakerOption {
+	return func(c *CircuitBreakerCompleter) {
+		c.threshold = n
+	}
+}
+
+// BreakerCooldown sets how long the circuit stays open before a test request is let through, default is 30 seconds.
+func BreakerCooldown(d time.Duration) CircuitBreakerOption {
+	return func(c *CircuitBreakerCompleter) {
+		c.cooldown = d
+	}
+}
+
+// BreakerClassifier defines which errors are counted as failures of the provider.
+func BreakerClassifier(failure func(err error) bool) CircuitBreakerOption {
+	return func(c *CircuitBreakerCompleter) {
+		c.classify = failure
+	}
+}
+
+func NewCircuitBreakerCompleter(completer ChatCompleter, opts ...CircuitBreakerOption) *CircuitBreakerCompleter {
+	c := &CircuitBreakerCompleter{
+		completer: completer,
+		threshold: 5,
+		cooldown:  30 * time.Second,
+		classify: func(err error) bool {
+			// bad requests are caller's fault, they don't signal provider outage
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && !apiErr.Retryable {
+				return false
+			}
+
+			return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *CircuitBreakerCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	probe, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.completer.Complete(ctx, req)
+
+	c.release(probe, err)
+
+	return resp, err
+}
+
+// acquire checks if request can be sent, it returns true if the request is a probe in half-open state.
+func (c *CircuitBreakerCompleter) acquire() (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.open {
+		return false, nil
+	}
+
+	if c.probing || time.Since(c.openedAt) < c.cooldown {
+		return false, ErrCircuitOpen
+	}
+
+	c.probing = true
+	return true, nil
+}
+
+func (c *CircuitBreakerCompleter) release(probe bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if probe {
+		c.probing = false
+	}
+
+	if err == nil {
+		c.failures = 0
+		c.open = false
+		return
+	}
+
+	if !c.classify(err) {
+		return
+	}
+
+	c.failures++
+
+	if probe || c.failures >= c.threshold {
+		c.open = true
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Cache stores completion responses by request hash.
+type Cache interface {
+	Get(ctx context.Context, key string) (*CompletionResponse, bool)
+	Set(ctx context.Context, key string, resp *CompletionResponse)
+}
+
+// CacheCompleter serves repeated deterministic requests from cache instead of calling the underlying completer.
+// By default only requests with temperature 0 are cached, use CacheAlways to cache all requests.
+type CacheCompleter struct {
+	completer ChatCompleter
+	cache     Cache
+	always    bool
+	exclude   map[string]bool
+}
+
+type CacheOption func(*CacheCompleter)
+
+// CacheBackend replaces default in-memory LRU cache.
+func CacheBackend(cache Cache) CacheOption {
+	return func(c *CacheCompleter) {
+		c.cache = cache
+	}
+}
+
+// CacheAlways enables caching regardless of temperature.
+func CacheAlways() CacheOption {
+	return func(c *CacheCompleter) {
+		c.always = true
+	}
+}
+
+// CacheExcludeTools disables caching for requests offering any of the given tools, use it for tools whose results vary between calls.
+func CacheExcludeTools(names ...string) CacheOption {
+	return func(c *CacheCompleter) {
+		for _, name := range names {
+			c.exclude[name] = true
+		}
+	}
+}
+
+func NewCacheCompleter(completer ChatCompleter, opts ...CacheOption) *CacheCompleter {
+	c := &CacheCompleter{
+		completer: completer,
+		cache:     NewLRUCache(1000),
+		exclude:   map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *CacheCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if !c.cacheable(req) {
+		return c.completer.Complete(ctx, req)
+	}
+
+	key, err := hashRequest(req)
+	if err != nil {
+		return c.completer.Complete(ctx, req)
+	}
+
+	if resp, ok := c.cache.Get(ctx, key); ok {
+		resp = copyResponse(resp)
+
+		if req.StreamCallback != nil {
+			if err := replay(ctx, req.StreamCallback, resp); err != nil {
+				return nil, err
+			}
+		}
+
+		return resp, nil
+	}
+
+	resp, err := c.completer.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.Set(ctx, key, copyResponse(resp))
+
+	return resp, nil
+}
+
+func (c *CacheCompleter) cacheable(req CompletionRequest) bool {
+	for _, tool := range req.Tools {
+		if c.exclude[tool.Name] {
+			return false
+		}
+	}
+
+	return c.always || (req.Temperature != nil && *req.Temperature == 0)
+}
+
+// hashRequest calculates hash of the request parameters which affect completion.
+func hashRequest(req CompletionRequest) (string, error) {
+	type typed struct {
+		Type    string  `json:"type"`
+		Message Message `json:"message"`
+	}
+
+	messages := make([]typed, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = typed{Type: fmt.Sprintf("%T", m), Message: m}
+	}
+
+	data, err := json.Marshal(struct {
+		Model             string          `json:"model"`
+		Messages          []typed         `json:"messages"`
+		Tools             []Tool          `json:"tools"`
+		ToolChoice        ToolChoice      `json:"tool_choice"`
+		ParallelToolCalls bool            `json:"parallel_tool_calls"`
+		MaxTokens         *int64          `json:"max_tokens"`
+		Temperature       *float32        `json:"temperature"`
+		TopP              *float32        `json:"top_p"`
+		TopK              *int32          `json:"top_k"`
+		Seed              *int64          `json:"seed"`
+		Container         *Container      `json:"container"`
+		Betas             []string        `json:"betas"`
+		Reasoning         *Reasoning      `json:"reasoning"`
+		Format            *ResponseFormat `json:"response_format"`
+		LogProbs          bool            `json:"logprobs"`
+		TopLogProbs       int             `json:"top_logprobs"`
+		Extra             map[string]any  `json:"extra"`
+	}{
+		Model:             req.Model,
+		Messages:          messages,
+		Tools:             req.Tools,
+		ToolChoice:        req.ToolChoice,
+		ParallelToolCalls: req.ParallelToolCalls,
+		MaxTokens:         req.MaxTokens,
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		TopK:              req.TopK,
+		Seed:              req.Seed,
+		Container:         req.Container,
+		Betas:             req.Betas,
+		Reasoning:         req.Reasoning,
+		Format:            req.ResponseFormat,
+		LogProbs:          req.LogProbs,
+		TopLogProbs:       req.TopLogProbs,
+		Extra:             req.Extra,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// copyResponse copies the response deep enough for the cached copy not to share tool calls, results and log
+// probabilities with the response returned to the caller.
+func copyResponse(resp *CompletionResponse) *CompletionResponse {
+	c := *resp
+	c.Content = copyMessage(AssistantMessage{Content: resp.Content}).(AssistantMessage).Content
+	c.LogProbs = copyLogProbs(resp.LogProbs)
+
+	return &c
+}
+
+func copyLogProbs(probs []TokenLogProb) []TokenLogProb {
+	if probs == nil {
+		return nil
+	}
+
+	c := make([]TokenLogProb, len(probs))
+	for i, p := range probs {
+		c[i] = p
+		c[i].Top = copyLogProbs(p.Top)
+	}
+
+	return c
+}
+
+// replay sends cached response to the stream callback as if it was streamed by the model.
+func replay(ctx context.Context, callback func(ctx context.Context, chunk Chunk) error, resp *CompletionResponse) error {
+	for index, block := range resp.Content {
+		var chunks []Chunk
+
+		switch block.Type {
+		case MessageBlockTypeText:
+			chunks = append(chunks, Chunk{Type: StreamChunkTypeText, Index: index, Text: block.Text})
+		case MessageBlockTypeReasoning:
+			chunks = append(chunks, Chunk{Type: StreamChunkTypeReasoning, Index: index, Text: block.Text})
+		case MessageBlockTypeToolCall:
+			chunks = append(chunks,
+				Chunk{Type: StreamChunkTypeToolCallStart, Index: index, Call: &ToolCall{ID: block.ToolCall.ID, Name: block.ToolCall.Name}},
+				Chunk{Type: StreamChunkTypeToolCallDelta, Index: index, Call: block.ToolCall},
+			)
+		}
+
+		for _, chunk := range chunks {
+			if err := callback(ctx, chunk); err != nil {
+				return err
+			}
+		}
+	}
+
+	usage := resp.Usage
+	if err := callback(ctx, Chunk{Type: StreamChunkTypeUsage, Usage: &usage}); err != nil {
+		return err
+	}
+
+	return callback(ctx, Chunk{Type: StreamChunkTypeFinish, FinishReason: resp.FinishReason})
+}
+
+// LRUCache keeps a limited number of completion responses in-memory, evicting the least recently used ones.
+type LRUCache struct {
+	lock  sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CompletionResponse
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) (*CompletionResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(item)
+	return item.Value.(*lruEntry).resp, true
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, resp *CompletionResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if item, ok := c.items[key]; ok {
+		item.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(item)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, resp: resp})
+
+	for c.size > 0 && c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*lruEntry).key)
+	}
+}
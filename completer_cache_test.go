@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashRequest(t *testing.T) {
+	base := func() CompletionRequest {
+		return CompletionRequest{
+			Model:    "model",
+			Messages: []Message{NewSystemMessage("Be brief"), NewUserMessage("Hello")},
+		}
+	}
+
+	tests := map[string]func(req *CompletionRequest){
+		"model":               func(req *CompletionRequest) { req.Model = "other" },
+		"messages":            func(req *CompletionRequest) { req.Messages = []Message{NewUserMessage("Hello")} },
+		"message type":        func(req *CompletionRequest) { req.Messages[0] = NewDeveloperMessage("Be brief") },
+		"tools":               func(req *CompletionRequest) { req.Tools = []Tool{{Name: "search"}} },
+		"parallel tool calls": func(req *CompletionRequest) { req.ParallelToolCalls = true },
+		"container":           func(req *CompletionRequest) { req.Container = &Container{ID: "container"} },
+		"betas":               func(req *CompletionRequest) { req.Betas = []string{"beta"} },
+		"reasoning":           func(req *CompletionRequest) { req.Reasoning = &Reasoning{Enabled: true, Budget: 2048} },
+		"response format":     func(req *CompletionRequest) { req.ResponseFormat = &ResponseFormat{Name: "response"} },
+		"log probabilities":   func(req *CompletionRequest) { req.LogProbs = true },
+		"extra":               func(req *CompletionRequest) { req.Extra = map[string]any{"service_tier": "flex"} },
+	}
+
+	want, err := hashRequest(base())
+	if err != nil {
+		t.Fatalf("Unable to hash request: %v", err)
+	}
+
+	t.Run("same request", func(t *testing.T) {
+		req := base()
+		req.IdempotencyKey = "key"
+		req.StreamCallback = func(ctx context.Context, chunk Chunk) error { return nil }
+
+		got, err := hashRequest(req)
+		if err != nil {
+			t.Fatalf("Unable to hash request: %v", err)
+		}
+
+		if got != want {
+			t.Errorf("Expected the same hash for requests differing only in idempotency key and callback")
+		}
+	})
+
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := base()
+			change(&req)
+
+			got, err := hashRequest(req)
+			if err != nil {
+				t.Fatalf("Unable to hash request: %v", err)
+			}
+
+			if got == want {
+				t.Errorf("Expected hash to change when %s changes", name)
+			}
+		})
+	}
+}
+
+func TestCacheCompleter(t *testing.T) {
+	completer := NewCacheCompleter(&countingCompleter{resp: &CompletionResponse{
+		Model:        "model",
+		Content:      []MessageBlock{{Type: MessageBlockTypeToolCall, ToolCall: &ToolCall{ID: "call_1", Name: "search", Arguments: `{"q":"go"}`}}},
+		FinishReason: FinishReasonToolCalls,
+		LogProbs:     []TokenLogProb{{Token: "a", LogProb: -0.1, Top: []TokenLogProb{{Token: "b", LogProb: -2}}}},
+	}}, CacheAlways())
+
+	req := CompletionRequest{Model: "model", Messages: []Message{NewUserMessage("Search go")}}
+
+	// caller modifies the response it got, cached response must not change
+	first, err := completer.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	first.Content[0].ToolCall.Arguments = "{}"
+	first.LogProbs[0].Top[0].Token = "c"
+
+	second, err := completer.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	second.Content[0].ToolCall.ID = "call_2"
+
+	third, err := completer.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if calls := completer.completer.(*countingCompleter).calls; calls != 1 {
+		t.Errorf("Expected 1 call to the underlying completer, got %d", calls)
+	}
+
+	if got := third.Content[0].ToolCall; got.ID != "call_1" || got.Arguments != `{"q":"go"}` {
+		t.Errorf("Expected cached tool call to be intact, got %+v", got)
+	}
+
+	if got := third.LogProbs[0].Top[0].Token; got != "b" {
+		t.Errorf("Expected cached log probabilities to be intact, got token %q", got)
+	}
+}
+
+// countingCompleter returns copies of the same response and counts calls.
+type countingCompleter struct {
+	resp  *CompletionResponse
+	calls int
+}
+
+func (c *countingCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	c.calls++
+	return copyResponse(c.resp), nil
+}
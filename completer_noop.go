@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+)
+
+// NoopCompleter always replies with a fixed text and finishes the turn.
+// It's useful for wiring tests of tools, memory and tracing without calling a model.
+type NoopCompleter struct {
+	text string
+}
+
+func NewNoopCompleter(text string) *NoopCompleter {
+	return &NoopCompleter{text: text}
+}
+
+func (c *NoopCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return fixedResponse(ctx, req, c.text)
+}
+
+// EchoCompleter replies with the last user message.
+type EchoCompleter struct{}
+
+func NewEchoCompleter() *EchoCompleter {
+	return &EchoCompleter{}
+}
+
+func (c *EchoCompleter) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	text := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if m, ok := req.Messages[i].(UserMessage); ok {
+			text = m.Content
+			break
+		}
+	}
+
+	return fixedResponse(ctx, req, text)
+}
+
+func fixedResponse(ctx context.Context, req CompletionRequest, text string) (*CompletionResponse, error) {
+	resp := &CompletionResponse{
+		Model:        req.Model,
+		Content:      []MessageBlock{{Type: MessageBlockTypeText, Text: text}},
+		FinishReason: FinishReasonStop,
+	}
+
+	if req.StreamCallback != nil {
+		if err := replay(ctx, req.StreamCallback, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	contextMemory contextKey = iota
+	contextToolStream
+	contextSemaphore
+	contextRunID
+)
+
+// MemoryFromContext returns memory of the agent run, it's available to tools called by the agent.
+func MemoryFromContext(ctx context.Context) (Memory, bool) {
+	m, ok := ctx.Value(contextMemory).(Memory)
+	return m, ok
+}
+
+func withMemory(ctx context.Context, m Memory) context.Context {
+	return context.WithValue(ctx, contextMemory, m)
+}
+
+// RunIDFromContext returns ID of the agent run, it's available to tools called by the agent.
+func RunIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextRunID).(string)
+	return id, ok
+}
+
+func withRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextRunID, id)
+}
+
+// ToolValue returns a value added with WithToolContext from the context of a tool call.
+func ToolValue[T any](ctx context.Context, key any) (T, bool) {
+	v, ok := ctx.Value(key).(T)
+	return v, ok
+}
+
+// acquire takes a slot of the semaphore shared by nested agent runs, it blocks until the slot is available or the
+// context is done. The returned function releases the slot.
+func acquire(ctx context.Context) (func(), error) {
+	sem, ok := ctx.Value(contextSemaphore).(chan struct{})
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// withSemaphore adds the semaphore to the context, unless the context already has one (i.e. the run is nested), so
+// the limit of the outermost run applies to all nested runs.
+func withSemaphore(ctx context.Context, sem chan struct{}) context.Context {
+	if _, ok := ctx.Value(contextSemaphore).(chan struct{}); ok || sem == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextSemaphore, sem)
+}
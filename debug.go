@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// WithDebugDump writes every iteration of the agentic loop to the writer as indented JSON: the request with rendered
+// messages and tool schemas, the response of the model and results of tool calls. It's intended for debugging prompts
+// locally, use tracing in production.
+func WithDebugDump(w io.Writer) Option {
+	var lock sync.Mutex
+
+	return WithObserver(func(ctx context.Context, event IterationEvent) {
+		data, err := json.MarshalIndent(newDebugDump(event), "", "  ")
+		if err != nil {
+			slog.WarnContext(ctx, "Unable to dump iteration", "channel", "llm", "error", err)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			slog.WarnContext(ctx, "Unable to dump iteration", "channel", "llm", "error", err)
+		}
+	})
+}
+
+type debugDump struct {
+	Iteration int                 `json:"iteration"`
+	Request   debugRequest        `json:"request"`
+	Response  *CompletionResponse `json:"response"`
+	Results   []json.RawMessage   `json:"results,omitempty"`
+}
+
+type debugRequest struct {
+	Model       string            `json:"model"`
+	Messages    []json.RawMessage `json:"messages"`
+	Tools       []Tool            `json:"tools,omitempty"`
+	ToolChoice  string            `json:"tool_choice"`
+	MaxTokens   *int64            `json:"max_tokens,omitempty"`
+	Temperature *float32          `json:"temperature,omitempty"`
+	TopP        *float32          `json:"top_p,omitempty"`
+	TopK        *int32            `json:"top_k,omitempty"`
+	Reasoning   *Reasoning        `json:"reasoning,omitempty"`
+	Format      *ResponseFormat   `json:"response_format,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+}
+
+func newDebugDump(event IterationEvent) debugDump {
+	req := event.Request
+
+	return debugDump{
+		Iteration: event.Iteration,
+		Request: debugRequest{
+			Model:       req.Model,
+			Messages:    debugMessages(req.Messages),
+			Tools:       req.Tools,
+			ToolChoice:  req.ToolChoice.String(),
+			MaxTokens:   req.MaxTokens,
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			TopK:        req.TopK,
+			Reasoning:   req.Reasoning,
+			Format:      req.ResponseFormat,
+			Extra:       req.Extra,
+		},
+		Response: event.Response,
+		Results:  debugMessages(event.Results),
+	}
+}
+
+// debugMessages encodes messages with their type, messages which can not be encoded are replaced with an error.
+func debugMessages(messages []Message) []json.RawMessage {
+	var result []json.RawMessage
+	for _, m := range messages {
+		data, err := encodeMessage(m)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+
+		result = append(result, data)
+	}
+
+	return result
+}
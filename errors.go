@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrContentFiltered is returned when the model refused to respond and the response was blocked by content filter.
+var ErrContentFiltered = errors.New("response has been blocked by content filter")
+
+// ErrEmptyResponse is returned when the model finished its reply without any text and without tool calls.
+var ErrEmptyResponse = errors.New("model returned an empty response")
+
+// ErrNoMessages is returned when the request has no messages, e.g. the agent has neither a prompt nor a conversation
+// in memory.
+var ErrNoMessages = errors.New("request has no messages, add a user message or a conversation to memory")
+
+// ErrMemoryInUse is returned when Run is called with memory used by another run which has not finished yet, concurrent
+// runs would interleave their messages in the same conversation. Give every concurrent run its own memory.
+var ErrMemoryInUse = errors.New("memory is used by another run, use WithMemory to give the run its own memory")
+
+// ErrTruncatedToolCalls is returned when a reply with tool calls is cut off by max tokens limit while continuation is
+// enabled, see WithContinueOnLength. The reply is not written to memory, increase max tokens to let the model finish.
+var ErrTruncatedToolCalls = errors.New("reply with tool calls has been cut off by max tokens limit")
+
+// ErrStuckLoop is returned when the model keeps calling the same tool with the same arguments, see WithLoopDetection.
+var ErrStuckLoop = errors.New("agent is stuck calling the same tool")
+
+// ErrCircuitOpen is returned by CircuitBreakerCompleter while the circuit is open and requests are not sent to the provider.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// APIError is a failure reported by the model provider. Completers convert errors of provider SDKs to APIError,
+// so callers can inspect them with errors.As regardless of the provider.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       string // provider specific error code, e.g. "rate_limit_exceeded" or "overloaded_error"
+	Message    string // human-readable error message
+	Retryable  bool   // true if the request may succeed when retried (rate limits, timeouts, server errors)
+	Err        error  // original error
+}
+
+// NewAPIError creates APIError, it's retryable if status code signals a temporary failure.
+func NewAPIError(statusCode int, code, message string, err error) *APIError {
+	retryable := statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusConflict ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= http.StatusInternalServerError
+
+	return &APIError{StatusCode: statusCode, Code: code, Message: message, Retryable: retryable, Err: err}
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("provider error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("provider error %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports if the request may succeed when retried.
+func (e *APIError) Temporary() bool {
+	return e.Retryable
+}
+
+// UnknownToolError is returned when the model calls a tool which does not exist, it lists tools with similar names,
+// so the model can recover from a misspelled name.
+type UnknownToolError struct {
+	Name        string   // name of the tool called by the model
+	Suggestions []string // names of available tools close to the name, the closest first
+}
+
+// NewUnknownToolError creates UnknownToolError suggesting up to 3 tools with names close to the given one.
+func NewUnknownToolError(name string, tools []Tool) *UnknownToolError {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	limit := max(2, len(name)/3)
+
+	var candidates []candidate
+	for _, tool := range tools {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(tool.Name)); d <= limit {
+			candidates = append(candidates, candidate{name: tool.Name, distance: d})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	err := &UnknownToolError{Name: name}
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		err.Suggestions = append(err.Suggestions, candidates[i].name)
+	}
+
+	return err
+}
+
+func (e *UnknownToolError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("unknown tool %q", e.Name)
+	}
+
+	quoted := make([]string, len(e.Suggestions))
+	for i, name := range e.Suggestions {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+
+	return fmt.Sprintf("unknown tool %q, did you mean %s?", e.Name, strings.Join(quoted, " or "))
+}
+
+// levenshtein calculates edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// ErrBudgetExceeded is returned when the run is stopped because its cost would exceed the limit set by WithMaxCost.
+var ErrBudgetExceeded = errors.New("cost budget has been exceeded")
+
+// unavailable tells if the error means the model is overloaded or temporarily unavailable.
+func unavailable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return true
+	default:
+		return apiErr.Code == "overloaded_error"
+	}
+}
@@ -0,0 +1,84 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/eolymp/go-agent/openai"
+	"github.com/openai/openai-go/option"
+)
+
+// BaseURL is the endpoint of Gemini OpenAI-compatible API.
+const BaseURL = "https://generativelanguage.googleapis.com/v1beta/openai/"
+
+// New creates a chat completer for the public Gemini API with the given API key.
+//
+// Gemini exposes OpenAI-compatible API, so the OpenAI completer is configured to use Gemini endpoint. Parameters which
+// Gemini does not support (parallel_tool_calls and Idempotency-Key header) are removed from requests, developer messages
+// are sent with system role. Use Gemini model names, e.g. "gemini-2.5-flash". Additional options are passed to the
+// OpenAI client.
+func New(apiKey string, opts ...option.RequestOption) *openai.Completer {
+	return openai.NewCompatible(append(compatible(
+		option.WithBaseURL(BaseURL),
+		option.WithAPIKey(apiKey),
+	), opts...)...)
+}
+
+// TokenFunc returns an OAuth2 access token for Google Cloud. With golang.org/x/oauth2/google it can be built from
+// Application Default Credentials:
+//
+//	ts, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+//	token := func(ctx context.Context) (string, error) {
+//		t, err := ts.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//
+//		return t.AccessToken, nil
+//	}
+type TokenFunc func(ctx context.Context) (string, error)
+
+// NewVertex creates a chat completer for Gemini models served by Google Cloud Vertex AI in the given project and
+// location (e.g. "us-central1" or "global"). Every request is authorized with a token returned by the token function,
+// the function is expected to cache tokens (oauth2.TokenSource does).
+//
+// Vertex AI exposes OpenAI-compatible API, so messages, tools and unsupported parameters are handled the same way as
+// for the public Gemini API. Use Vertex model names with publisher prefix, e.g. "google/gemini-2.5-flash". Additional options are passed to
+// the OpenAI client.
+func NewVertex(projectID, location string, token TokenFunc, opts ...option.RequestOption) *openai.Completer {
+	return openai.NewCompatible(append(compatible(
+		option.WithBaseURL(VertexURL(projectID, location)),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			t, err := token(req.Context())
+			if err != nil {
+				return nil, fmt.Errorf("unable to get Google Cloud access token: %w", err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next(req)
+		}),
+	), opts...)...)
+}
+
+// compatible adds options removing parameters which Gemini OpenAI-compatible API does not support. Both are removed by
+// middleware, because per-request options of the completer are applied after client options.
+func compatible(opts ...option.RequestOption) []option.RequestOption {
+	return append(opts,
+		openai.WithoutParams("parallel_tool_calls"),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			req.Header.Del("Idempotency-Key")
+			return next(req)
+		}),
+	)
+}
+
+// VertexURL returns the endpoint of Vertex AI OpenAI-compatible API in the given project and location.
+func VertexURL(projectID, location string) string {
+	host := location + "-aiplatform.googleapis.com"
+	if location == "global" {
+		host = "aiplatform.googleapis.com"
+	}
+
+	return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/endpoints/openapi/", host, projectID, location)
+}
@@ -0,0 +1,100 @@
+package gemini_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+	"github.com/eolymp/go-agent/gemini"
+	"github.com/eolymp/go-agent/openai"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/openai/openai-go/option"
+)
+
+func TestCompleter(t *testing.T) {
+	var body map[string]any
+	var header http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /models":
+			_, _ = io.WriteString(w, `{"object":"list","data":[{"id":"gemini-2.5-flash","object":"model"}]}`)
+		case "POST /chat/completions":
+			body = nil
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Unable to decode request: %v", err)
+			}
+
+			_, _ = io.WriteString(w, `{"id":"1","object":"chat.completion","model":"gemini-2.5-flash","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	token := func(ctx context.Context) (string, error) {
+		return "token", nil
+	}
+
+	tests := map[string]struct {
+		completer     *openai.Completer
+		authorization string
+	}{
+		"gemini": {
+			completer:     gemini.New("key", option.WithBaseURL(srv.URL), option.WithMaxRetries(0)),
+			authorization: "Bearer key",
+		},
+		"vertex": {
+			completer:     gemini.NewVertex("project", "global", token, option.WithBaseURL(srv.URL), option.WithMaxRetries(0)),
+			authorization: "Bearer token",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Run("ping", func(t *testing.T) {
+				if err := tc.completer.Ping(context.Background()); err != nil {
+					t.Fatalf("Ping failed: %v", err)
+				}
+
+				if got := header.Get("Authorization"); got != tc.authorization {
+					t.Errorf("Expected authorization %q, got %q", tc.authorization, got)
+				}
+			})
+
+			t.Run("complete", func(t *testing.T) {
+				resp, err := tc.completer.Complete(context.Background(), agent.CompletionRequest{
+					Model:             "gemini-2.5-flash",
+					Messages:          []agent.Message{agent.NewUserMessage("Say hello")},
+					Tools:             []agent.Tool{{Name: "greet", InputSchema: &jsonschema.Schema{Type: "object"}}},
+					ParallelToolCalls: true,
+					IdempotencyKey:    "key-1",
+				})
+
+				if err != nil {
+					t.Fatalf("Complete failed: %v", err)
+				}
+
+				if got := resp.Content[0].Text; got != "Hello" {
+					t.Errorf("Expected reply %q, got %q", "Hello", got)
+				}
+
+				if _, ok := body["parallel_tool_calls"]; ok {
+					t.Error("Expected parallel_tool_calls to be removed from the request")
+				}
+
+				if got := header.Get("Idempotency-Key"); got != "" {
+					t.Errorf("Expected Idempotency-Key header to be removed, got %q", got)
+				}
+			})
+		})
+	}
+}
@@ -5,14 +5,18 @@ go 1.25.7
 require (
 	github.com/anthropics/anthropic-sdk-go v1.26.0
 	github.com/braintrustdata/braintrust-go v0.8.0
+	github.com/coder/websocket v1.8.15
 	github.com/google/jsonschema-go v0.4.2
 	github.com/google/uuid v1.6.0
 	github.com/hoisie/mustache v0.0.0-20160804235033-6375acf62c69
 	github.com/openai/openai-go v1.12.0
+	github.com/pkoukk/tiktoken-go v0.1.8
+	github.com/pkoukk/tiktoken-go-loader v0.0.2
 	golang.org/x/sync v0.19.0
 )
 
 require (
+	github.com/dlclark/regexp2 v1.10.0 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.2.0 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
@@ -0,0 +1,104 @@
+package groq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eolymp/go-agent/openai"
+	"github.com/openai/openai-go/option"
+)
+
+// BaseURL is the endpoint of Groq OpenAI-compatible API.
+const BaseURL = "https://api.groq.com/openai/v1/"
+
+// New creates a chat completer for Groq with the given API key.
+//
+// Groq exposes OpenAI-compatible API, so the OpenAI completer is configured to use Groq endpoint. Parameters which
+// Groq rejects (such as parallel_tool_calls) are removed from requests, developer messages are sent with system role.
+// Usage of streamed responses, which Groq reports in x_groq field, is moved to the usage field of the stream event.
+// Use Groq model names, e.g. "llama-3.3-70b-versatile".
+// Additional options are passed to the OpenAI client.
+func New(apiKey string, opts ...option.RequestOption) *openai.Completer {
+	return openai.NewCompatible(append([]option.RequestOption{
+		option.WithBaseURL(BaseURL),
+		option.WithAPIKey(apiKey),
+		openai.WithoutParams("parallel_tool_calls"),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+				return resp, err
+			}
+
+			resp.Body = &usageReader{body: resp.Body, lines: bufio.NewReader(resp.Body)}
+			return resp, nil
+		}),
+	}, opts...)...)
+}
+
+// usageReader reads Server-Sent Events of a streamed response and copies usage from x_groq field of events to the
+// usage field, where the OpenAI client expects it.
+type usageReader struct {
+	body  io.ReadCloser
+	lines *bufio.Reader
+	buf   []byte
+	err   error
+}
+
+func (r *usageReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 && r.err == nil {
+		var line []byte
+		line, r.err = r.lines.ReadBytes('\n')
+		r.buf = withUsage(line)
+	}
+
+	if len(r.buf) == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+func (r *usageReader) Close() error {
+	return r.body.Close()
+}
+
+// withUsage returns the event line with usage copied from x_groq field, other lines are returned as is.
+func withUsage(line []byte) []byte {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return line
+	}
+
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return line
+	}
+
+	var ext struct {
+		Usage json.RawMessage `json:"usage"`
+	}
+
+	if err := json.Unmarshal(event["x_groq"], &ext); err != nil || len(ext.Usage) == 0 {
+		return line
+	}
+
+	if usage := event["usage"]; len(usage) != 0 && string(usage) != "null" {
+		return line
+	}
+
+	event["usage"] = ext.Usage
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return line
+	}
+
+	return append(append([]byte("data: "), data...), '\n')
+}
@@ -0,0 +1,112 @@
+package groq_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+	"github.com/eolymp/go-agent/groq"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/openai/openai-go/option"
+)
+
+func TestCompleter(t *testing.T) {
+	var body map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /models":
+			_, _ = io.WriteString(w, `{"object":"list","data":[{"id":"llama-3.3-70b-versatile","object":"model"}]}`)
+		case "POST /chat/completions":
+			body = nil
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Unable to decode request: %v", err)
+			}
+
+			if body["stream"] == true {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"llama-3.3-70b-versatile\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hello\"}}]}\n\n")
+				_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"llama-3.3-70b-versatile\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],\"x_groq\":{\"id\":\"req_1\",\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1,\"total_tokens\":4}}}\n\n")
+				_, _ = io.WriteString(w, "data: [DONE]\n\n")
+				return
+			}
+
+			_, _ = io.WriteString(w, `{"id":"1","object":"chat.completion","model":"llama-3.3-70b-versatile","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	completer := groq.New("key", option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+
+	t.Run("ping", func(t *testing.T) {
+		if err := completer.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping failed: %v", err)
+		}
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		resp, err := completer.Complete(context.Background(), agent.CompletionRequest{
+			Model:             "llama-3.3-70b-versatile",
+			Messages:          []agent.Message{agent.NewUserMessage("Say hello")},
+			Tools:             []agent.Tool{{Name: "greet", InputSchema: &jsonschema.Schema{Type: "object"}}},
+			ParallelToolCalls: true,
+		})
+
+		if err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+
+		if got := resp.Content[0].Text; got != "Hello" {
+			t.Errorf("Expected reply %q, got %q", "Hello", got)
+		}
+
+		if _, ok := body["parallel_tool_calls"]; ok {
+			t.Error("Expected parallel_tool_calls to be removed from the request")
+		}
+
+		if _, ok := body["tools"]; !ok {
+			t.Error("Expected tools to be sent")
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var usage *agent.CompletionUsage
+
+		resp, err := completer.Complete(context.Background(), agent.CompletionRequest{
+			Model:    "llama-3.3-70b-versatile",
+			Messages: []agent.Message{agent.NewUserMessage("Say hello")},
+			StreamCallback: func(ctx context.Context, chunk agent.Chunk) error {
+				if chunk.Type == agent.StreamChunkTypeUsage {
+					usage = chunk.Usage
+				}
+
+				return nil
+			},
+		})
+
+		if err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+
+		if got := resp.Content[0].Text; got != "Hello" {
+			t.Errorf("Expected reply %q, got %q", "Hello", got)
+		}
+
+		if resp.Usage.PromptTokens != 3 || resp.Usage.CompletionTokens != 1 || resp.Usage.TotalTokens != 4 {
+			t.Errorf("Expected usage reported in x_groq field, got %+v", resp.Usage)
+		}
+
+		if usage == nil || usage.TotalTokens != 4 {
+			t.Errorf("Expected usage chunk to be streamed, got %+v", usage)
+		}
+	})
+}
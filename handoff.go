@@ -4,19 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/google/jsonschema-go/jsonschema"
 )
 
+// Handoff is returned by a tool (see WithHandoffTool) to transfer the conversation to another agent, the run stops and
+// returns it, so the caller can continue the conversation with the agent. The call handing over the conversation gets
+// a result noting the transfer. Other calls of the same batch are cancelled: calls not started yet are skipped, calls
+// still running are reported as cancelled and their results are discarded (their side effects are not rolled back).
+// Calls completed before the handoff keep their results, so every tool call of the conversation has a result. The
+// message of the handoff is written to memory as an assistant message after the results.
 type Handoff struct {
-	Agent *Agent
+	Agent   *Agent
+	Message string // message for the agent taking over the conversation, optional
 }
 
 func (Handoff) Error() string {
 	return "handed over"
 }
 
-func WithHandoffTool(agents ...*Agent) Option {
+func WithHandoffTool(agents []*Agent, opts ...DelegationOption) Option {
 	type HandoffRequest struct {
 		Specialist string `json:"specialist"`
 		Message    string `json:"message"`
@@ -33,32 +38,25 @@ func WithHandoffTool(agents ...*Agent) Option {
 	}
 
 	list := Tool{
-		Name:        "list_specialists",
-		Description: "List available specialists",
+		Name:        "list_handoff_specialists",
+		Description: "List specialists the conversation can be handed over to",
 	}
 
 	delegate := Tool{
 		Name:        "delegate_to",
 		Description: "Handoff conversation to a specialist who can provide expert-level assistance for the user",
-		InputSchema: &jsonschema.Schema{
-			Type:                 "object",
-			AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
-			Required:             []string{"specialist"},
-			Properties: map[string]*jsonschema.Schema{
-				"specialist": {
-					Type:        "string",
-					Description: "name of the specialist in charge of handling the conversation",
-				},
-				"message": {
-					Type:        "string",
-					Description: "a description of the task for specialist",
-				},
-			},
-		},
+		InputSchema: SchemaObject(
+			Required("specialist", SchemaString("name of the specialist in charge of handling the conversation")),
+			Optional("message", SchemaString("a description of the task for specialist")),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(&delegate, &list)
 	}
 
 	return func(agent *Agent) {
-		opts := []Option{
+		tools := []Option{
 			WithTool(list, func(ctx context.Context, in []byte) (any, error) {
 				var items []SpecialistDesc
 				for _, a := range agents {
@@ -78,21 +76,15 @@ func WithHandoffTool(agents ...*Agent) Option {
 						continue
 					}
 
-					if req.Message != "" {
-						if err := agent.memory.Append(ctx, NewAssistantMessage(req.Message)); err != nil {
-							return "", err
-						}
-					}
-
-					return "", Handoff{Agent: a}
+					return "", Handoff{Agent: a, Message: req.Message}
 				}
 
 				return nil, fmt.Errorf("specialist %q does not exist, valid values: %v", req.Specialist, names)
 			}),
 		}
 
-		for _, opt := range opts {
-			opt(agent)
+		for _, tool := range tools {
+			tool(agent)
 		}
 	}
 }
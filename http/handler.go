@@ -0,0 +1,167 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/eolymp/go-agent"
+)
+
+// Request is the payload accepted by the Handler.
+type Request struct {
+	Message string   `json:"message,omitempty"` // user message, can be empty when resuming the conversation after tool approval
+	Approve []string `json:"approve,omitempty"` // ids of tool calls approved by the user
+	Reject  []string `json:"reject,omitempty"`  // ids of tool calls rejected by the user
+}
+
+// Handler runs an agent for every request and streams chunks to the client as Server-Sent Events.
+//
+// Every chunk is sent as an event named after the chunk type (see agent.StreamChunkType) with JSON encoded chunk as data.
+// Once the agent finishes, the handler sends `done` event with the final reply. If the agent requires tool approval,
+// `approval_request` event is sent with the list of calls to approve, any other failure is reported with `error` event.
+type Handler struct {
+	agent  *agent.Agent
+	memory func(r *http.Request) (agent.Memory, error)
+}
+
+type HandlerOption func(*Handler)
+
+// WithMemory defines how conversation memory is loaded for the request, by default every request starts a new conversation.
+func WithMemory(loader func(r *http.Request) (agent.Memory, error)) HandlerOption {
+	return func(h *Handler) {
+		h.memory = loader
+	}
+}
+
+func NewHandler(a *agent.Agent, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		agent: a,
+		memory: func(r *http.Request) (agent.Memory, error) {
+			return agent.NewStaticMemory(), nil
+		},
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	memory, err := h.memory(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to load memory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Message != "" {
+		if err := memory.Append(ctx, agent.NewUserMessage(req.Message)); err != nil {
+			http.Error(w, fmt.Sprintf("unable to save message: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s := &stream{Memory: memory, w: w, flusher: flusher}
+
+	reply, err := h.agent.Run(ctx, agent.WithMemory(s), agent.WithApprovals(req.Approve...), agent.WithRejections(req.Reject...))
+
+	// client is gone, nothing to report
+	if ctx.Err() != nil {
+		return
+	}
+
+	var approval agent.ToolApprovalRequest
+
+	switch {
+	case errors.As(err, &approval):
+		_ = s.send("approval_request", approval)
+	case err != nil:
+		_ = s.send("error", map[string]string{"error": err.Error()})
+	default:
+		_ = s.send("done", reply)
+	}
+}
+
+// stream wraps memory to forward chunks to the client.
+type stream struct {
+	agent.Memory
+	lock    sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *stream) Stream(ctx context.Context, chunk agent.Chunk) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if st, ok := s.Memory.(agent.Streamer); ok {
+		if err := st.Stream(ctx, chunk); err != nil {
+			return err
+		}
+	}
+
+	return s.send(chunk.Type.String(), chunk)
+}
+
+// Unwrap implements agent.WrappedMemory, so concurrent runs are detected on the underlying memory.
+func (s *stream) Unwrap() agent.Memory {
+	return s.Memory
+}
+
+// Replace implements agent.ReplaceableMemory, so the agent can compact the conversation if the underlying memory supports it.
+func (s *stream) Replace(ctx context.Context, messages []agent.Message) error {
+	m, ok := s.Memory.(agent.ReplaceableMemory)
+	if !ok {
+		return fmt.Errorf("memory %T does not support replacing messages", s.Memory)
+	}
+
+	return m.Replace(ctx, messages)
+}
+
+func (s *stream) send(event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,154 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+	agenthttp "github.com/eolymp/go-agent/http"
+)
+
+func TestHandler(t *testing.T) {
+	t.Run("compaction tool replaces memory behind the handler", func(t *testing.T) {
+		memory := agent.NewStaticMemory()
+		for i := 0; i < 4; i++ {
+			_ = memory.Append(context.Background(), agent.NewUserMessage(fmt.Sprintf("question %d", i)))
+			_ = memory.Append(context.Background(), agent.NewAssistantMessage(fmt.Sprintf("answer %d", i)))
+		}
+
+		completer := &script{responses: []*agent.CompletionResponse{
+			reply(agent.MessageBlock{Type: agent.MessageBlockTypeToolCall, ToolCall: &agent.ToolCall{ID: "call_1", Name: "compact_context", Arguments: "{}"}}),
+			reply(agent.MessageBlock{Type: agent.MessageBlockTypeText, Text: "Compacted"}),
+		}}
+
+		a := agent.New("assistant",
+			agent.WithChatCompleter(completer),
+			agent.WithModel("model"),
+			agent.WithCompactionTool(agent.NewNoopCompleter("Summary"), "model"),
+			agent.WithAutoApproveTools("compact_context"),
+		)
+
+		events := post(t, agenthttp.NewHandler(a, agenthttp.WithMemory(func(r *http.Request) (agent.Memory, error) {
+			return memory, nil
+		})), "compact")
+
+		if !strings.Contains(events, "event: done") {
+			t.Fatalf("Expected the run to finish, got events:\n%s", events)
+		}
+
+		messages := memory.List()
+		if len(messages) == 0 {
+			t.Fatal("Expected memory to keep messages")
+		}
+
+		if m, ok := messages[0].(agent.SystemMessage); !ok || !strings.Contains(m.Content, "Summary") {
+			t.Errorf("Expected memory to start with the summary, got %#v", messages[0])
+		}
+	})
+
+	t.Run("concurrent runs on the same memory", func(t *testing.T) {
+		memory := agent.NewStaticMemory()
+		completer := &gate{started: make(chan struct{}), release: make(chan struct{})}
+
+		h := agenthttp.NewHandler(agent.New("assistant", agent.WithChatCompleter(completer), agent.WithModel("model")),
+			agenthttp.WithMemory(func(r *http.Request) (agent.Memory, error) {
+				return memory, nil
+			}),
+		)
+
+		first := make(chan string)
+		go func() {
+			first <- post(t, h, "first")
+		}()
+
+		<-completer.started
+
+		if events := post(t, h, "second"); !strings.Contains(events, "event: error") || !strings.Contains(events, agent.ErrMemoryInUse.Error()) {
+			t.Errorf("Expected the second run to fail with ErrMemoryInUse, got events:\n%s", events)
+		}
+
+		close(completer.release)
+
+		if events := <-first; !strings.Contains(events, "event: done") {
+			t.Errorf("Expected the first run to finish, got events:\n%s", events)
+		}
+	})
+}
+
+// post sends a message to the handler and returns the event stream of the response.
+func post(t *testing.T, h http.Handler, message string) string {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(fmt.Sprintf(`{"message":%q}`, message)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Unable to read response: %v", err)
+	}
+
+	return string(data)
+}
+
+// script replies with queued responses, one per completion request.
+type script struct {
+	lock      sync.Mutex
+	responses []*agent.CompletionResponse
+}
+
+func (s *script) Complete(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+
+	return resp, nil
+}
+
+// gate holds the first reply until it's released, so a run can be kept in progress, other replies are sent at once.
+type gate struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *gate) Complete(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
+	first := false
+	g.once.Do(func() {
+		first = true
+		close(g.started)
+	})
+
+	if first {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return reply(agent.MessageBlock{Type: agent.MessageBlockTypeText, Text: "Done"}), nil
+}
+
+func reply(blocks ...agent.MessageBlock) *agent.CompletionResponse {
+	return &agent.CompletionResponse{Model: "model", Content: blocks, FinishReason: agent.FinishReasonStop}
+}
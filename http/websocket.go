@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/eolymp/go-agent"
+)
+
+// Frame types exchanged over WebSocket connection.
+const (
+	FrameMessage         = "message"          // client sends a user message
+	FrameApproval        = "approval"         // client approves or rejects tool calls
+	FrameChunk           = "chunk"            // server streams a chunk of the reply
+	FrameApprovalRequest = "approval_request" // server asks to approve tool calls
+	FrameError           = "error"            // server reports a failure
+	FrameDone            = "done"             // server sends the final reply
+)
+
+// Frame is a JSON message sent over WebSocket connection in either direction.
+type Frame struct {
+	Type    string                  `json:"type"`
+	Message string                  `json:"message,omitempty"`
+	Approve []string                `json:"approve,omitempty"`
+	Reject  []string                `json:"reject,omitempty"`
+	Chunk   *agent.Chunk            `json:"chunk,omitempty"`
+	Calls   []agent.ToolCall        `json:"calls,omitempty"`
+	Reply   *agent.AssistantMessage `json:"reply,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// WebSocketHandler keeps a conversation with an agent over a single WebSocket connection.
+//
+// The client sends `message` frames, each message is appended to the memory and the agent is run, streaming `chunk`
+// frames back and finishing with `done` or `error` frame. When the agent requires tool approval, the handler sends
+// `approval_request` frame and waits for `approval` frame before resuming the run.
+//
+// Memory is loaded once per connection using WithMemory option.
+type WebSocketHandler struct {
+	Handler
+	origins []string
+}
+
+type WebSocketOption func(*WebSocketHandler)
+
+// WithOriginPatterns allows cross-origin connections from hosts matching given patterns, see websocket.AcceptOptions.
+func WithOriginPatterns(patterns ...string) WebSocketOption {
+	return func(h *WebSocketHandler) {
+		h.origins = append(h.origins, patterns...)
+	}
+}
+
+// WithHandlerOptions applies options shared with Handler, such as WithMemory.
+func WithHandlerOptions(opts ...HandlerOption) WebSocketOption {
+	return func(h *WebSocketHandler) {
+		for _, opt := range opts {
+			opt(&h.Handler)
+		}
+	}
+}
+
+func NewWebSocketHandler(a *agent.Agent, opts ...WebSocketOption) *WebSocketHandler {
+	h := &WebSocketHandler{Handler: *NewHandler(a)}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	memory, err := h.memory(r)
+	if err != nil {
+		http.Error(w, "unable to load memory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.origins})
+	if err != nil {
+		return
+	}
+
+	defer conn.CloseNow()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// read frames in background, so the run is cancelled as soon as the client disconnects
+	frames := make(chan Frame)
+	go func() {
+		defer cancel()
+		defer close(frames)
+
+		for {
+			var frame Frame
+			if err := wsjson.Read(ctx, conn, &frame); err != nil {
+				return
+			}
+
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s := &socket{Memory: memory, conn: conn}
+
+	// calls awaiting approval, run is resumed only after the client decides on them
+	var pending []agent.ToolCall
+
+	for frame := range frames {
+		var opts []agent.Option
+
+		switch frame.Type {
+		case FrameMessage:
+			if len(pending) > 0 {
+				_ = s.send(ctx, Frame{Type: FrameError, Error: "tool approval is pending"})
+				continue
+			}
+
+			if err := memory.Append(ctx, agent.NewUserMessage(frame.Message)); err != nil {
+				_ = s.send(ctx, Frame{Type: FrameError, Error: err.Error()})
+				continue
+			}
+
+		case FrameApproval:
+			if len(pending) == 0 {
+				_ = s.send(ctx, Frame{Type: FrameError, Error: "there are no tool calls awaiting approval"})
+				continue
+			}
+
+			opts = append(opts, agent.WithApprovals(frame.Approve...), agent.WithRejections(frame.Reject...))
+
+		default:
+			_ = s.send(ctx, Frame{Type: FrameError, Error: "unknown frame type: " + frame.Type})
+			continue
+		}
+
+		pending = nil
+
+		reply, err := h.agent.Run(ctx, append(opts, agent.WithMemory(s))...)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var approval agent.ToolApprovalRequest
+
+		switch {
+		case errors.As(err, &approval):
+			pending = approval.Calls
+			_ = s.send(ctx, Frame{Type: FrameApprovalRequest, Calls: approval.Calls})
+		case err != nil:
+			_ = s.send(ctx, Frame{Type: FrameError, Error: err.Error()})
+		default:
+			_ = s.send(ctx, Frame{Type: FrameDone, Reply: &reply})
+		}
+	}
+
+	_ = conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// socket wraps memory to forward chunks to the client.
+type socket struct {
+	agent.Memory
+	lock sync.Mutex
+	conn *websocket.Conn
+}
+
+func (s *socket) Stream(ctx context.Context, chunk agent.Chunk) error {
+	if st, ok := s.Memory.(agent.Streamer); ok {
+		if err := st.Stream(ctx, chunk); err != nil {
+			return err
+		}
+	}
+
+	return s.send(ctx, Frame{Type: FrameChunk, Chunk: &chunk})
+}
+
+// Unwrap implements agent.WrappedMemory, so concurrent runs are detected on the underlying memory.
+func (s *socket) Unwrap() agent.Memory {
+	return s.Memory
+}
+
+// Replace implements agent.ReplaceableMemory, so the agent can compact the conversation if the underlying memory supports it.
+func (s *socket) Replace(ctx context.Context, messages []agent.Message) error {
+	m, ok := s.Memory.(agent.ReplaceableMemory)
+	if !ok {
+		return fmt.Errorf("memory %T does not support replacing messages", s.Memory)
+	}
+
+	return m.Replace(ctx, messages)
+}
+
+func (s *socket) send(ctx context.Context, frame Frame) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return wsjson.Write(ctx, s.conn, frame)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// modelMaxTokens is the max number of output tokens of known models. Models are matched by exact name or by name with
+// a snapshot suffix (e.g. "gpt-4o-2024-08-06" or "claude-3-7-sonnet-latest"), so newer models of the family (e.g.
+// "claude-opus-4-6") are not clamped to limits of older ones.
+var modelMaxTokens = map[string]int64{
+	"claude-3-haiku":    4096,
+	"claude-3-opus":     4096,
+	"claude-3-5-haiku":  8192,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-7-sonnet": 64000,
+	"claude-sonnet-4":   64000,
+	"claude-sonnet-4-5": 64000,
+	"claude-haiku-4-5":  64000,
+	"claude-opus-4":     32000,
+	"claude-opus-4-1":   32000,
+	"claude-opus-4-5":   64000,
+	"gpt-3.5-turbo":     4096,
+	"gpt-4-turbo":       4096,
+	"gpt-4o":            16384,
+	"gpt-4o-mini":       16384,
+	"gpt-4.1":           32768,
+	"gpt-4.1-mini":      32768,
+	"gpt-4.1-nano":      32768,
+	"gpt-5":             128000,
+	"gpt-5-mini":        128000,
+	"gpt-5-nano":        128000,
+	"o1":                100000,
+	"o1-mini":           65536,
+	"o3":                100000,
+	"o3-mini":           100000,
+	"o4-mini":           100000,
+}
+
+// SetModelMaxTokens sets the max number of output tokens of the model (and its snapshots), use it to add new models or
+// override the built-in values. It's not safe for concurrent use, call it on startup.
+func SetModelMaxTokens(model string, maxTokens int64) {
+	modelMaxTokens[model] = maxTokens
+}
+
+// ModelMaxTokens returns the max number of output tokens of the model, it returns false if the model is unknown.
+func ModelMaxTokens(model string) (int64, bool) {
+	if value, ok := modelMaxTokens[model]; ok {
+		return value, true
+	}
+
+	for name, value := range modelMaxTokens {
+		if suffix, ok := strings.CutPrefix(model, name); ok && snapshot.MatchString(suffix) {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+// snapshot matches suffixes of model snapshots: a date (-20250514 or -2024-08-06) or -latest.
+var snapshot = regexp.MustCompile(`^-(\d{8}|\d{4}-\d{2}-\d{2}|latest)$`)
+
+// lookupModel finds a value for the model by exact name or by the longest prefix.
+func lookupModel[T any](values map[string]T, model string) (T, bool) {
+	if value, ok := values[model]; ok {
+		return value, true
+	}
+
+	var match string
+	for name := range values {
+		if strings.HasPrefix(model, name) && len(name) > len(match) {
+			match = name
+		}
+	}
+
+	if match == "" {
+		var zero T
+		return zero, false
+	}
+
+	return values[match], true
+}
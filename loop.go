@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// LoopAction defines what the agent does when it detects that the model is stuck calling the same tool.
+type LoopAction int
+
+const (
+	LoopActionNudge LoopAction = iota // add a user message asking the model to change approach
+	LoopActionAbort                   // stop the run with ErrStuckLoop
+)
+
+// loopDetector tracks tool calls of the run to detect the model calling the same tool with the same arguments in
+// consecutive turns. Identical calls made in parallel within one turn are counted once.
+type loopDetector struct {
+	limit   int
+	repeats map[string]int // number of consecutive turns each call was made in
+}
+
+// add records tool calls of a turn and returns the call repeated in limit turns in a row, if any.
+func (d *loopDetector) add(calls []ToolCall) (ToolCall, bool) {
+	var stuck ToolCall
+	var found bool
+
+	repeats := make(map[string]int, len(calls))
+	for _, call := range calls {
+		args := bytes.Buffer{}
+		if err := json.Compact(&args, []byte(call.Arguments)); err != nil {
+			args.WriteString(call.Arguments)
+		}
+
+		sig := call.Name + ":" + args.String()
+		if _, ok := repeats[sig]; ok {
+			continue
+		}
+
+		repeats[sig] = d.repeats[sig] + 1
+
+		if d.limit > 0 && repeats[sig] >= d.limit && !found {
+			stuck, found = call, true
+		}
+	}
+
+	d.repeats = repeats
+
+	return stuck, found
+}
+
+// reset forgets calls recorded so far.
+func (d *loopDetector) reset() {
+	d.repeats = nil
+}
+
+func nudge(call ToolCall, repeats int) string {
+	return fmt.Sprintf("You have called `%s` with the same arguments %d times in a row without making progress. "+
+		"Do not repeat this call, try a different approach or respond with what you have so far.", call.Name, repeats)
+}
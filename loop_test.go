@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"testing"
+)
+
+func TestLoopDetector(t *testing.T) {
+	call := func(name, args string) ToolCall {
+		return ToolCall{Name: name, Arguments: args}
+	}
+
+	tests := map[string]struct {
+		limit int
+		turns [][]ToolCall
+		want  []bool // whether each turn is reported as stuck
+	}{
+		"different calls": {
+			limit: 2,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`)}, {call("search", `{"q":"b"}`)}, {call("read", `{"q":"b"}`)}},
+			want:  []bool{false, false, false},
+		},
+		"same call in consecutive turns": {
+			limit: 2,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`)}, {call("search", `{"q":"a"}`)}},
+			want:  []bool{false, true},
+		},
+		"parallel identical calls count once": {
+			limit: 2,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`), call("search", `{"q":"a"}`), call("search", `{"q":"a"}`)}},
+			want:  []bool{false},
+		},
+		"arguments differing in whitespace": {
+			limit: 2,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`)}, {call("search", `{ "q": "a" }`)}},
+			want:  []bool{false, true},
+		},
+		"repeats are counted across turns with other calls": {
+			limit: 3,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`)}, {call("search", `{"q":"a"}`), call("read", `{}`)}, {call("search", `{"q":"a"}`)}},
+			want:  []bool{false, false, true},
+		},
+		"interrupted sequence": {
+			limit: 2,
+			turns: [][]ToolCall{{call("search", `{"q":"a"}`)}, {call("read", `{}`)}, {call("search", `{"q":"a"}`)}},
+			want:  []bool{false, false, false},
+		},
+		"detection is disabled": {
+			limit: 0,
+			turns: [][]ToolCall{{call("search", `{}`)}, {call("search", `{}`)}, {call("search", `{}`)}},
+			want:  []bool{false, false, false},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := loopDetector{limit: tc.limit}
+
+			for i, calls := range tc.turns {
+				if _, got := d.add(calls); got != tc.want[i] {
+					t.Errorf("add() in turn %d = %v, want %v", i, got, tc.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("reset", func(t *testing.T) {
+		d := loopDetector{limit: 2}
+		d.add([]ToolCall{call("search", `{}`)})
+		d.reset()
+
+		if _, got := d.add([]ToolCall{call("search", `{}`)}); got {
+			t.Error("add() after reset reported a loop")
+		}
+	})
+}
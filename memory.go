@@ -2,6 +2,8 @@ package agent
 
 import (
 	"context"
+	"reflect"
+	"sync"
 )
 
 // Memory provides a memorization capability for an agent.
@@ -10,6 +12,40 @@ type Memory interface {
 	Append(ctx context.Context, m Message) error
 }
 
+// claimed holds memories used by runs in progress.
+var claimed sync.Map
+
+// WrappedMemory is a memory which adds behavior to another memory (e.g. streams chunks to a client), wrappers are often
+// created for a single run, so runs are serialized on the memory returned by Unwrap.
+type WrappedMemory interface {
+	Memory
+	Unwrap() Memory
+}
+
+// claim marks the memory as used by a run, it returns false if the memory is already used by another run. The
+// returned function releases the memory. Wrapped memories are claimed by the memory they wrap. Memories of
+// non-comparable types can not be tracked and are not claimed.
+func claim(memory Memory) (func(), bool) {
+	for {
+		w, ok := memory.(WrappedMemory)
+		if !ok || w.Unwrap() == nil {
+			break
+		}
+
+		memory = w.Unwrap()
+	}
+
+	if memory == nil || !reflect.TypeOf(memory).Comparable() {
+		return func() {}, true
+	}
+
+	if _, loaded := claimed.LoadOrStore(memory, struct{}{}); loaded {
+		return nil, false
+	}
+
+	return func() { claimed.Delete(memory) }, true
+}
+
 func LastMessage(memory Memory) (Message, bool) {
 	messages := memory.List()
 	if len(messages) == 0 {
@@ -30,3 +66,82 @@ func LastMessageAsUser(memory Memory) (UserMessage, bool) {
 	um, ok := last.(UserMessage)
 	return um, ok
 }
+
+// ReplaceableMemory is a memory which allows to replace the whole conversation, e.g. to compact it.
+type ReplaceableMemory interface {
+	Memory
+	Replace(ctx context.Context, messages []Message) error
+}
+
+// limitMessages keeps system messages and at most limit most recent other messages. Tool results are not kept without
+// the tool calls they belong to.
+func limitMessages(messages []Message, limit int) []Message {
+	var system, other []Message
+	for _, m := range messages {
+		switch m.(type) {
+		case SystemMessage, DeveloperMessage:
+			system = append(system, m)
+		default:
+			other = append(other, m)
+		}
+	}
+
+	if len(other) <= limit {
+		return messages
+	}
+
+	other = other[len(other)-limit:]
+	for len(other) > 0 && isToolOutput(other[0]) {
+		other = other[1:]
+	}
+
+	return append(system, other...)
+}
+
+// dedupeMessages drops messages identical to the previous one, except user messages.
+func dedupeMessages(messages []Message) []Message {
+	var result []Message
+	for i, m := range messages {
+		if _, ok := m.(UserMessage); !ok && i > 0 && reflect.DeepEqual(m, messages[i-1]) {
+			continue
+		}
+
+		result = append(result, m)
+	}
+
+	return result
+}
+
+// retainToolResults replaces outputs of tool calls made before the last turns user messages with a placeholder. Tool
+// calls are kept, so the model knows which tools were called, and every call still has a result.
+func retainToolResults(messages []Message, turns int) []Message {
+	start := -1
+	for i := len(messages) - 1; i >= 0 && turns > 0; i-- {
+		if _, ok := messages[i].(UserMessage); ok {
+			start = i
+			turns--
+		}
+	}
+
+	if turns > 0 || start < 0 {
+		return messages
+	}
+
+	result := make([]Message, len(messages))
+	for i, m := range messages {
+		switch v := m.(type) {
+		case ToolResult:
+			if i < start {
+				m = NewToolResult(v.CallID, "[result of an earlier turn is omitted]")
+			}
+		case ToolError:
+			if i < start {
+				m = NewToolResult(v.CallID, "[error of an earlier turn is omitted]")
+			}
+		}
+
+		result[i] = m
+	}
+
+	return result
+}
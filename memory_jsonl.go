@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONLMemory keeps messages in-memory and appends each of them to a file as a JSON line, when the file is opened
+// again, messages are loaded from it, so the conversation survives restarts.
+type JSONLMemory struct {
+	lock     sync.Mutex
+	file     *os.File
+	messages []Message
+}
+
+// NewJSONLMemory opens (or creates) the file and loads messages written to it before. If the process stopped while
+// writing the last line, the incomplete line is cut off, so new messages are not appended to it.
+func NewJSONLMemory(filename string) (*JSONLMemory, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &JSONLMemory{file: file}
+
+	// offset of the end of the last complete line
+	var offset int64
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			_ = file.Close()
+			return nil, err
+		}
+
+		complete := !errors.Is(err, io.EOF)
+		if complete {
+			offset += int64(len(data))
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			msg, derr := decodeMessage(data)
+
+			// the last line may be incomplete if the process stopped while writing it
+			if derr != nil && complete {
+				_ = file.Close()
+				return nil, fmt.Errorf("%s:%d: %w", filename, line, derr)
+			}
+
+			if derr == nil {
+				m.messages = append(m.messages, msg)
+			}
+
+			if !complete {
+				if err := m.repair(offset, derr == nil); err != nil {
+					_ = file.Close()
+					return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+				}
+			}
+		}
+
+		if !complete {
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// repair fixes the last line which has no line break: a valid message is terminated with a line break, otherwise the
+// file is truncated to the end of the last complete line.
+func (m *JSONLMemory) repair(offset int64, valid bool) error {
+	if valid {
+		_, err := m.file.Write([]byte{'\n'})
+		return err
+	}
+
+	return m.file.Truncate(offset)
+}
+
+func (m *JSONLMemory) Append(ctx context.Context, msg Message) error {
+	data, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := m.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *JSONLMemory) List() []Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Close closes the underlying file.
+func (m *JSONLMemory) Close() error {
+	return m.file.Close()
+}
+
+// jsonlMessage is a message with a type discriminator.
+type jsonlMessage struct {
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message"`
+}
+
+func encodeMessage(m Message) ([]byte, error) {
+	var kind string
+
+	switch m.(type) {
+	case SystemMessage:
+		kind = "system"
+	case DeveloperMessage:
+		kind = "developer"
+	case UserMessage:
+		kind = "user"
+	case AssistantMessage:
+		kind = "assistant"
+	case ToolResult:
+		kind = "tool_result"
+	case ToolError:
+		kind = "tool_error"
+	default:
+		return nil, fmt.Errorf("message type %T is not supported", m)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonlMessage{Type: kind, Message: data})
+}
+
+func decodeMessage(data []byte) (Message, error) {
+	var envelope jsonlMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+
+	switch envelope.Type {
+	case "system":
+		return decodeAs[SystemMessage](envelope.Message)
+	case "developer":
+		return decodeAs[DeveloperMessage](envelope.Message)
+	case "user":
+		return decodeAs[UserMessage](envelope.Message)
+	case "assistant":
+		return decodeAs[AssistantMessage](envelope.Message)
+	case "tool_result":
+		return decodeAs[ToolResult](envelope.Message)
+	case "tool_error":
+		return decodeAs[ToolError](envelope.Message)
+	default:
+		return nil, fmt.Errorf("unknown message type %q", envelope.Type)
+	}
+}
+
+func decodeAs[T Message](data []byte) (Message, error) {
+	var m T
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package agent_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+)
+
+func TestJSONLMemory(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "memory.jsonl")
+
+	messages := []agent.Message{
+		agent.NewSystemMessage("You are a helpful assistant."),
+		agent.NewUserMessage("What is the weather?"),
+		agent.AssistantMessage{Content: []agent.MessageBlock{
+			{Type: agent.MessageBlockTypeToolCall, ToolCall: &agent.ToolCall{ID: "call_1", Name: "weather", Arguments: `{"city":"Kyiv"}`}},
+		}},
+		agent.NewToolResult("call_1", "sunny"),
+		agent.NewAssistantMessage("It's sunny."),
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		memory := openJSONL(t, filename)
+		for _, m := range messages {
+			if err := memory.Append(ctx, m); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+		}
+
+		_ = memory.Close()
+
+		if got := openJSONL(t, filename).List(); !reflect.DeepEqual(got, messages) {
+			t.Errorf("List() = %#v, want %#v", got, messages)
+		}
+	})
+
+	t.Run("torn tail", func(t *testing.T) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// simulate the process stopped while writing the last line
+		if err := os.WriteFile(filename, append(data, `{"type":"user","message":{"con`...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		memory := openJSONL(t, filename)
+		if got := memory.List(); !reflect.DeepEqual(got, messages) {
+			t.Errorf("List() = %#v, want %#v", got, messages)
+		}
+
+		if err := memory.Append(ctx, agent.NewUserMessage("Thanks!")); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+
+		_ = memory.Close()
+
+		want := append(append([]agent.Message(nil), messages...), agent.NewUserMessage("Thanks!"))
+		if got := openJSONL(t, filename).List(); !reflect.DeepEqual(got, want) {
+			t.Errorf("List() after reopen = %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("missing line break", func(t *testing.T) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filename, data[:len(data)-1], 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		memory := openJSONL(t, filename)
+		if err := memory.Append(ctx, agent.NewUserMessage("Bye!")); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+
+		_ = memory.Close()
+
+		if got := openJSONL(t, filename).List(); len(got) != len(messages)+2 {
+			t.Errorf("List() after reopen has %d messages, want %d", len(got), len(messages)+2)
+		}
+	})
+}
+
+func openJSONL(t *testing.T, filename string) *agent.JSONLMemory {
+	t.Helper()
+
+	memory, err := agent.NewJSONLMemory(filename)
+	if err != nil {
+		t.Fatalf("NewJSONLMemory failed: %v", err)
+	}
+
+	t.Cleanup(func() { _ = memory.Close() })
+
+	return memory
+}
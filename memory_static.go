@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 )
 
@@ -23,9 +24,75 @@ func (m *StaticMemory) Append(ctx context.Context, msg Message) error {
 	return nil
 }
 
+// List returns a copy of messages, so it's safe to use while other goroutines append to the memory.
 func (m *StaticMemory) List() []Message {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	return m.messages
+	return append([]Message(nil), m.messages...)
+}
+
+func (m *StaticMemory) Replace(ctx context.Context, messages []Message) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.messages = append([]Message(nil), messages...)
+	return nil
+}
+
+// Fork returns an independent copy of the memory, so the conversation can continue in two directions.
+// Messages are copied deep enough for branches not to share tool calls and results, however tool results holding
+// custom structures (other than strings and byte slices) are shared between branches and must not be mutated.
+func (m *StaticMemory) Fork() *StaticMemory {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	messages := make([]Message, len(m.messages))
+	for i, msg := range m.messages {
+		messages[i] = copyMessage(msg)
+	}
+
+	return &StaticMemory{messages: messages}
+}
+
+func copyMessage(m Message) Message {
+	switch v := m.(type) {
+	case AssistantMessage:
+		content := make([]MessageBlock, len(v.Content))
+		for i, block := range v.Content {
+			content[i] = block
+
+			if block.ToolCall != nil {
+				call := *block.ToolCall
+				content[i].ToolCall = &call
+			}
+
+			if block.ToolResult != nil {
+				result := copyToolResult(*block.ToolResult)
+				content[i].ToolResult = &result
+			}
+		}
+
+		v.Content = content
+		return v
+	case ToolResult:
+		return copyToolResult(v)
+	default:
+		return m
+	}
+}
+
+func copyToolResult(r ToolResult) ToolResult {
+	if r.Attachments != nil {
+		r.Attachments = append([]Attachment(nil), r.Attachments...)
+	}
+
+	switch v := r.Result.(type) {
+	case []byte:
+		r.Result = append([]byte(nil), v...)
+	case json.RawMessage:
+		r.Result = append(json.RawMessage(nil), v...)
+	}
+
+	return r
 }
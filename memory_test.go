@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitMessages(t *testing.T) {
+	system := NewSystemMessage("Be brief")
+	call := AssistantMessage{Content: []MessageBlock{{Type: MessageBlockTypeToolCall, ToolCall: &ToolCall{ID: "1", Name: "search"}}}}
+	result := NewToolResult("1", "found")
+
+	tests := map[string]struct {
+		messages []Message
+		limit    int
+		want     []Message
+	}{
+		"under the limit": {
+			messages: []Message{system, NewUserMessage("a"), NewAssistantMessage("b")},
+			limit:    2,
+			want:     []Message{system, NewUserMessage("a"), NewAssistantMessage("b")},
+		},
+		"keeps the most recent messages": {
+			messages: []Message{system, NewUserMessage("a"), NewAssistantMessage("b"), NewUserMessage("c"), NewAssistantMessage("d")},
+			limit:    2,
+			want:     []Message{system, NewUserMessage("c"), NewAssistantMessage("d")},
+		},
+		"system messages are kept in front": {
+			messages: []Message{NewUserMessage("a"), system, NewAssistantMessage("b"), NewDeveloperMessage("dev"), NewUserMessage("c")},
+			limit:    1,
+			want:     []Message{system, NewDeveloperMessage("dev"), NewUserMessage("c")},
+		},
+		"tool results are not kept without their calls": {
+			messages: []Message{NewUserMessage("a"), call, result, NewAssistantMessage("b")},
+			limit:    2,
+			want:     []Message{NewAssistantMessage("b")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := limitMessages(tc.messages, tc.limit); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("limitMessages() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetainToolResults(t *testing.T) {
+	call := func(id string) AssistantMessage {
+		return AssistantMessage{Content: []MessageBlock{{Type: MessageBlockTypeToolCall, ToolCall: &ToolCall{ID: id, Name: "search"}}}}
+	}
+
+	tests := map[string]struct {
+		messages []Message
+		turns    int
+		want     []Message
+	}{
+		"results of earlier turns are omitted": {
+			messages: []Message{
+				NewUserMessage("a"), call("1"), NewToolResult("1", "found"), ToolError{CallID: "2", Error: "failed"},
+				NewUserMessage("b"), call("3"), NewToolResult("3", "found"),
+			},
+			turns: 1,
+			want: []Message{
+				NewUserMessage("a"), call("1"), NewToolResult("1", "[result of an earlier turn is omitted]"), NewToolResult("2", "[error of an earlier turn is omitted]"),
+				NewUserMessage("b"), call("3"), NewToolResult("3", "found"),
+			},
+		},
+		"fewer turns than retained": {
+			messages: []Message{NewUserMessage("a"), call("1"), NewToolResult("1", "found")},
+			turns:    2,
+			want:     []Message{NewUserMessage("a"), call("1"), NewToolResult("1", "found")},
+		},
+		"no user messages": {
+			messages: []Message{call("1"), NewToolResult("1", "found")},
+			turns:    1,
+			want:     []Message{call("1"), NewToolResult("1", "found")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := retainToolResults(tc.messages, tc.turns); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("retainToolResults() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDedupeMessages(t *testing.T) {
+	system := NewSystemMessage("Be brief")
+
+	tests := map[string]struct {
+		messages []Message
+		want     []Message
+	}{
+		"consecutive duplicates": {
+			messages: []Message{system, system, NewAssistantMessage("a"), NewAssistantMessage("a")},
+			want:     []Message{system, NewAssistantMessage("a")},
+		},
+		"user messages are kept": {
+			messages: []Message{NewUserMessage("a"), NewUserMessage("a")},
+			want:     []Message{NewUserMessage("a"), NewUserMessage("a")},
+		},
+		"duplicates which are not consecutive": {
+			messages: []Message{NewAssistantMessage("a"), NewUserMessage("b"), NewAssistantMessage("a")},
+			want:     []Message{NewAssistantMessage("a"), NewUserMessage("b"), NewAssistantMessage("a")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := dedupeMessages(tc.messages); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("dedupeMessages() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"strings"
 	"time"
 
 	"github.com/hoisie/mustache"
@@ -10,15 +11,51 @@ type Message interface {
 	isMessage()
 }
 
-func render(m Message, values map[string]any) Message {
-	if values == nil {
-		values = map[string]any{}
+var defaultValues map[string]any
+
+// SetDefaultValues sets template values available in messages of all agents (e.g. environment or region), values
+// given to the agent take precedence over them.
+func SetDefaultValues(values map[string]any) {
+	defaultValues = values
+}
+
+// templateValues merges values for template substitution: reserved values (date, time and datetime, also available
+// under "_sys" key, e.g. {{_sys.date}}), default values, default values of the agent and values of the agent. Values
+// defined later take precedence, so reserved values never overwrite values given by the user.
+func templateValues(reserved bool, agentDefaults, agentValues map[string]any) map[string]any {
+	values := make(map[string]any, len(defaultValues)+len(agentDefaults)+len(agentValues)+4)
+
+	if reserved {
+		now := time.Now()
+		sys := map[string]any{
+			"date":     now.Format(time.DateOnly),
+			"time":     now.Format(time.TimeOnly),
+			"datetime": now.Format(time.RFC3339),
+		}
+
+		for k, v := range sys {
+			values[k] = v
+		}
+
+		values["_sys"] = sys
+	}
+
+	for k, v := range defaultValues {
+		values[k] = v
+	}
+
+	for k, v := range agentDefaults {
+		values[k] = v
 	}
 
-	values["date"] = time.Now().Format(time.DateOnly)
-	values["time"] = time.Now().Format(time.TimeOnly)
-	values["datetime"] = time.Now().Format(time.RFC3339)
+	for k, v := range agentValues {
+		values[k] = v
+	}
+
+	return values
+}
 
+func render(m Message, values map[string]any) Message {
 	switch v := m.(type) {
 	case AssistantMessage:
 		content := make([]MessageBlock, len(v.Content))
@@ -32,9 +69,39 @@ func render(m Message, values map[string]any) Message {
 		return AssistantMessage{Content: content}
 	case SystemMessage:
 		return SystemMessage{Content: mustache.Render(v.Content, values)}
+	case DeveloperMessage:
+		return DeveloperMessage{Content: mustache.Render(v.Content, values)}
 	case UserMessage:
 		return UserMessage{Content: mustache.Render(v.Content, values)}
 	default:
 		return m
 	}
 }
+
+// normalize moves system and developer messages in front of the conversation, so providers receive them in the same
+// order. System messages are coalesced into a single message, developer messages follow it.
+func normalize(messages []Message) []Message {
+	var system []string
+	var developer, conversation []Message
+
+	for _, m := range messages {
+		switch v := m.(type) {
+		case SystemMessage:
+			system = append(system, v.Content)
+		case DeveloperMessage:
+			developer = append(developer, m)
+		default:
+			conversation = append(conversation, m)
+		}
+	}
+
+	result := make([]Message, 0, len(messages))
+	if len(system) > 0 {
+		result = append(result, SystemMessage{Content: strings.Join(system, "\n\n")})
+	}
+
+	result = append(result, developer...)
+	result = append(result, conversation...)
+
+	return result
+}
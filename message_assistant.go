@@ -8,6 +8,7 @@ import (
 
 type AssistantMessage struct {
 	Content []MessageBlock `json:"content"`
+	RunID   string         `json:"run_id,omitempty"` // ID of the run which produced the message, see WithRunID
 }
 
 func NewAssistantMessage(text ...string) AssistantMessage {
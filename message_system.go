@@ -9,3 +9,15 @@ func NewSystemMessage(text string) SystemMessage {
 }
 
 func (m SystemMessage) isMessage() {}
+
+// DeveloperMessage carries instructions from the application developer. OpenAI reasoning models prefer developer
+// role over system, other providers treat it as a regular system message.
+type DeveloperMessage struct {
+	Content string `json:"content"`
+}
+
+func NewDeveloperMessage(text string) DeveloperMessage {
+	return DeveloperMessage{Content: text}
+}
+
+func (m DeveloperMessage) isMessage() {}
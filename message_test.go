@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]struct {
+		messages []Message
+		want     []Message
+	}{
+		"empty": {
+			messages: nil,
+			want:     []Message{},
+		},
+		"conversation only": {
+			messages: []Message{NewUserMessage("a"), NewAssistantMessage("b")},
+			want:     []Message{NewUserMessage("a"), NewAssistantMessage("b")},
+		},
+		"system messages are coalesced in front": {
+			messages: []Message{NewSystemMessage("first"), NewUserMessage("a"), NewSystemMessage("second")},
+			want:     []Message{NewSystemMessage("first\n\nsecond"), NewUserMessage("a")},
+		},
+		"developer messages follow system message": {
+			messages: []Message{NewDeveloperMessage("dev"), NewUserMessage("a"), NewSystemMessage("sys"), NewDeveloperMessage("late")},
+			want:     []Message{NewSystemMessage("sys"), NewDeveloperMessage("dev"), NewDeveloperMessage("late"), NewUserMessage("a")},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := normalize(tc.messages); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("normalize() = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
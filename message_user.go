@@ -15,8 +15,9 @@ func NewUserMessage(text string) UserMessage {
 }
 
 type ToolResult struct {
-	CallID string `json:"call_id"`
-	Result any    `json:"result"`
+	CallID      string       `json:"call_id"`
+	Result      any          `json:"result"`
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 func NewToolResult(callID string, result any) ToolResult {
@@ -40,8 +41,9 @@ func (c ToolResult) String() string {
 }
 
 type ToolError struct {
-	CallID string `json:"call_id"`
-	Error  string `json:"error"`
+	CallID  string `json:"call_id"`
+	Error   string `json:"error"`
+	Content string `json:"content,omitempty"` // error as presented to the model, if empty "ERROR: " + Error is used
 }
 
 func NewToolError(callID string, err string) ToolError {
@@ -51,5 +53,9 @@ func NewToolError(callID string, err string) ToolError {
 func (c ToolError) isMessage() {}
 
 func (c ToolError) String() string {
+	if c.Content != "" {
+		return c.Content
+	}
+
 	return "ERROR: " + c.Error
 }
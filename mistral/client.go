@@ -0,0 +1,97 @@
+package mistral
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/eolymp/go-agent"
+	"github.com/eolymp/go-agent/openai"
+	"github.com/openai/openai-go/option"
+)
+
+// BaseURL is the endpoint of Mistral La Plateforme API.
+const BaseURL = "https://api.mistral.ai/v1/"
+
+// Completer implements agent.ChatCompleter using Mistral chat completions API.
+//
+// The API is compatible with OpenAI, so messages, tools, finish reasons and usage are mapped by the OpenAI completer
+// (developer messages are sent with system role).
+// Mistral however requires tool call IDs to be exactly 9 alphanumeric characters, so IDs produced by other
+// providers (e.g. when the conversation is handed over from another model) are rewritten before sending.
+type Completer struct {
+	completer *openai.Completer
+}
+
+// New creates a new Mistral chat completer with the given API key.
+// Additional options are passed to the OpenAI client, use option.WithBaseURL to point it to a different deployment.
+func New(apiKey string, opts ...option.RequestOption) *Completer {
+	return &Completer{
+		completer: openai.NewCompatible(append([]option.RequestOption{option.WithBaseURL(BaseURL), option.WithAPIKey(apiKey)}, opts...)...),
+	}
+}
+
+// Complete implements agent.ChatCompleter.
+func (c *Completer) Complete(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
+	messages := make([]agent.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = normalizeCallIDs(m)
+	}
+
+	req.Messages = messages
+
+	return c.completer.Complete(ctx, req)
+}
+
+// Ping implements agent.Pinger by listing available models.
+func (c *Completer) Ping(ctx context.Context) error {
+	return c.completer.Ping(ctx)
+}
+
+// normalizeCallIDs rewrites tool call IDs in the message to the format accepted by Mistral.
+func normalizeCallIDs(m agent.Message) agent.Message {
+	switch v := m.(type) {
+	case agent.AssistantMessage:
+		content := make([]agent.MessageBlock, len(v.Content))
+		for i, block := range v.Content {
+			content[i] = block
+
+			if block.ToolCall != nil {
+				call := *block.ToolCall
+				call.ID = callID(call.ID)
+				content[i].ToolCall = &call
+			}
+		}
+
+		v.Content = content
+		return v
+	case agent.ToolResult:
+		v.CallID = callID(v.CallID)
+		return v
+	case agent.ToolError:
+		v.CallID = callID(v.CallID)
+		return v
+	default:
+		return m
+	}
+}
+
+// callID converts tool call ID to 9 alphanumeric characters, IDs which already match the format are kept as is.
+func callID(id string) string {
+	if len(id) == 9 && alphanumeric(id) {
+		return id
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])[:9]
+}
+
+func alphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"context"
+)
+
+// IterationEvent describes a single iteration of the agentic loop.
+type IterationEvent struct {
+	Iteration int                 // zero-based number of the iteration
+	Request   CompletionRequest   // request sent to the model
+	Response  *CompletionResponse // response of the model
+	Results   []Message           // results of tool calls requested in the response (ToolResult or ToolError)
+}
+
+// Calls returns tool calls requested by the model in this iteration.
+func (e IterationEvent) Calls() []ToolCall {
+	var calls []ToolCall
+	for _, block := range e.Response.Content {
+		if block.Type == MessageBlockTypeToolCall && block.ToolCall != nil {
+			calls = append(calls, *block.ToolCall)
+		}
+	}
+
+	return calls
+}
+
+// Observer is notified after every iteration of the agentic loop, see WithObserver.
+type Observer func(ctx context.Context, event IterationEvent)
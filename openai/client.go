@@ -1,8 +1,13 @@
 package openai
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 
 	"github.com/eolymp/go-agent"
@@ -12,7 +17,8 @@ import (
 )
 
 type Completer struct {
-	client openai.Client
+	client     openai.Client
+	compatible bool // API of another provider, which does not support developer role and user messages after tool results
 }
 
 // New creates a new OpenAI-based chat completer with the given options.
@@ -25,6 +31,14 @@ func New(opts ...option.RequestOption) *Completer {
 	return &Completer{client: openai.NewClient(opts...)}
 }
 
+// NewCompatible creates a chat completer for OpenAI-compatible API of another provider (e.g. Mistral, Groq or Gemini),
+// it accepts the same options as New. Developer messages are sent with system role, because such providers do not
+// support developer role. Some of them (e.g. Mistral) reject user messages between tool results and the next reply,
+// so attachments of tool results are not sent, the tool result notes them instead.
+func NewCompatible(opts ...option.RequestOption) *Completer {
+	return &Completer{client: openai.NewClient(opts...), compatible: true}
+}
+
 // NewWithClient creates a new OpenAI-based chat completer with an existing client.
 func NewWithClient(client openai.Client) *Completer {
 	return &Completer{client: client}
@@ -36,17 +50,74 @@ func (c *Completer) Complete(ctx context.Context, req agent.CompletionRequest) (
 		return c.stream(ctx, req)
 	}
 
-	resp, err := c.client.Chat.Completions.New(ctx, toOpenAIRequest(req))
+	params, err := toOpenAIRequest(c.convert(req))
 	if err != nil {
 		return nil, err
 	}
 
+	resp, err := c.client.Chat.Completions.New(ctx, params, requestOptions(req)...)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
 	return fromOpenAIResponse(resp), nil
 }
 
+// Ping implements agent.Pinger by listing available models.
+func (c *Completer) Ping(ctx context.Context) error {
+	if _, err := c.client.Models.List(ctx); err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// convert adapts messages for OpenAI-compatible APIs of other providers: developer messages are replaced with system
+// messages and attachments of tool results are replaced with a note in the result.
+func (c *Completer) convert(req agent.CompletionRequest) agent.CompletionRequest {
+	if !c.compatible {
+		return req
+	}
+
+	messages := make([]agent.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = m
+
+		switch v := m.(type) {
+		case agent.DeveloperMessage:
+			messages[i] = agent.NewSystemMessage(v.Content)
+		case agent.ToolResult:
+			if len(v.Attachments) == 0 {
+				continue
+			}
+
+			text := v.String()
+			for _, a := range v.Attachments {
+				text += "\n" + unsupported(a)
+			}
+
+			messages[i] = agent.ToolResult{CallID: v.CallID, Result: text}
+		}
+	}
+
+	req.Messages = messages
+	return req
+}
+
+func unsupported(a agent.Attachment) string {
+	return fmt.Sprintf("[attachment %q of type %s is not supported]", a.Name, a.MediaType)
+}
+
 // stream handles streaming completion with callback support.
 func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*agent.CompletionResponse, error) {
-	stream := c.client.Chat.Completions.NewStreaming(ctx, toOpenAIRequest(req))
+	params, err := toOpenAIRequest(c.convert(req))
+	if err != nil {
+		return nil, err
+	}
+
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := c.client.Chat.Completions.NewStreaming(ctx, params, requestOptions(req)...)
 
 	resp := &agent.CompletionResponse{}
 	calls := make(map[int]*agent.ToolCall)
@@ -118,19 +189,27 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 				}
 			}
 
+			resp.LogProbs = append(resp.LogProbs, fromOpenAILogProbs(event.Choices[0].Logprobs.Content)...)
+
 			if event.Choices[0].FinishReason != "" {
 				resp.FinishReason = mapFinishReason(event.Choices[0].FinishReason)
 			}
 		}
 
+		// usage is sent in the last chunk
+		if event.Usage.TotalTokens == 0 {
+			continue
+		}
+
 		resp.Usage.PromptTokens = int(event.Usage.PromptTokens)
 		resp.Usage.CompletionTokens = int(event.Usage.CompletionTokens)
 		resp.Usage.TotalTokens = int(event.Usage.TotalTokens)
 		resp.Usage.CachedPromptTokens = int(event.Usage.PromptTokensDetails.CachedTokens)
 
+		total := resp.Usage
 		chunk := agent.Chunk{
 			Type:  agent.StreamChunkTypeUsage,
-			Usage: &resp.Usage,
+			Usage: &total,
 		}
 
 		if err := req.StreamCallback(ctx, chunk); err != nil {
@@ -139,13 +218,14 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 	}
 
 	if err := stream.Err(); err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 
-	reason := resp.FinishReason
+	usage := resp.Usage
 	chunk := agent.Chunk{
 		Type:         agent.StreamChunkTypeFinish,
-		FinishReason: reason,
+		FinishReason: resp.FinishReason,
+		Usage:        &usage,
 	}
 
 	if err := req.StreamCallback(ctx, chunk); err != nil {
@@ -176,15 +256,15 @@ func (c *Completer) stream(ctx context.Context, req agent.CompletionRequest) (*a
 }
 
 // toOpenAIRequest converts a universal CompletionRequest to OpenAI-specific params.
-func toOpenAIRequest(req agent.CompletionRequest) openai.ChatCompletionNewParams {
-	params := openai.ChatCompletionNewParams{
-		Model:    req.Model,
-		Messages: make([]openai.ChatCompletionMessageParamUnion, len(req.Messages)),
+func toOpenAIRequest(req agent.CompletionRequest) (openai.ChatCompletionNewParams, error) {
+	messages, err := Export(req.Messages)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
 	}
 
-	// Convert messages
-	for i, msg := range req.Messages {
-		params.Messages[i] = messageToOpenAI(msg)
+	params := openai.ChatCompletionNewParams{
+		Model:    req.Model,
+		Messages: messages,
 	}
 
 	// Convert tools if present
@@ -217,11 +297,69 @@ func toOpenAIRequest(req agent.CompletionRequest) openai.ChatCompletionNewParams
 		params.TopP = openai.Float(float64(*req.TopP))
 	}
 
+	// Note: OpenAI does not support top_k sampling, req.TopK is ignored
+
+	// Note: OpenAI caches prompt prefixes automatically, req.CachedPrefix is not needed
+
+	if req.Seed != nil {
+		params.Seed = openai.Int(*req.Seed)
+	}
+
+	if req.LogProbs {
+		params.Logprobs = openai.Bool(true)
+
+		if req.TopLogProbs > 0 {
+			params.TopLogprobs = openai.Int(int64(req.TopLogProbs))
+		}
+	}
+
 	if req.Reasoning != nil && req.Reasoning.Effort != "" {
 		params.ReasoningEffort = openai.ReasoningEffort(req.Reasoning.Effort)
 	}
 
-	return params
+	if req.ResponseFormat != nil {
+		format, err := toOpenAIResponseFormat(*req.ResponseFormat)
+		if err != nil {
+			return openai.ChatCompletionNewParams{}, err
+		}
+
+		params.ResponseFormat = format
+	}
+
+	return params, nil
+}
+
+// toOpenAIResponseFormat converts response format to structured output if schema is given, and to JSON mode otherwise.
+// Schema is not enforced in strict mode, because strict mode does not support all schema features.
+func toOpenAIResponseFormat(format agent.ResponseFormat) (openai.ChatCompletionNewParamsResponseFormatUnion, error) {
+	if format.Schema == nil {
+		return openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONObject: &openai.ResponseFormatJSONObjectParam{}}, nil
+	}
+
+	data, err := json.Marshal(format.Schema)
+	if err != nil {
+		return openai.ChatCompletionNewParamsResponseFormatUnion{}, fmt.Errorf("response schema can not be serialized: %w", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return openai.ChatCompletionNewParamsResponseFormatUnion{}, fmt.Errorf("response schema can not be serialized: %w", err)
+	}
+
+	name := format.Name
+	if name == "" {
+		name = "response"
+	}
+
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   name,
+				Schema: schema,
+				Strict: openai.Bool(false),
+			},
+		},
+	}, nil
 }
 
 // fromOpenAIResponse converts an OpenAI response to a universal CompletionResponse.
@@ -237,6 +375,7 @@ func fromOpenAIResponse(resp *openai.ChatCompletion) *agent.CompletionResponse {
 		Model:        resp.Model,
 		Content:      fromOpenAIContent(choice.Message.Content, choice.Message.ToolCalls),
 		FinishReason: mapFinishReason(choice.FinishReason),
+		LogProbs:     fromOpenAILogProbs(choice.Logprobs.Content),
 		Usage: agent.CompletionUsage{
 			PromptTokens:       int(resp.Usage.PromptTokens),
 			CompletionTokens:   int(resp.Usage.CompletionTokens),
@@ -246,12 +385,30 @@ func fromOpenAIResponse(resp *openai.ChatCompletion) *agent.CompletionResponse {
 	}
 }
 
+// fromOpenAILogProbs converts OpenAI token log probabilities to the universal format.
+func fromOpenAILogProbs(tokens []openai.ChatCompletionTokenLogprob) []agent.TokenLogProb {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	result := make([]agent.TokenLogProb, len(tokens))
+	for i, token := range tokens {
+		result[i] = agent.TokenLogProb{Token: token.Token, LogProb: token.Logprob}
+
+		for _, top := range token.TopLogprobs {
+			result[i].Top = append(result[i].Top, agent.TokenLogProb{Token: top.Token, LogProb: top.Logprob})
+		}
+	}
+
+	return result
+}
+
 // mapFinishReason converts OpenAI's string finish reason to the universal FinishReason type.
 func mapFinishReason(reason string) agent.FinishReason {
 	switch reason {
 	case "stop":
 		return agent.FinishReasonStop
-	case "length":
+	case "length", "model_length": // model_length is returned by Mistral when context window is exhausted
 		return agent.FinishReasonLength
 	case "tool_calls":
 		return agent.FinishReasonToolCalls
@@ -289,21 +446,59 @@ func fromOpenAIContent(content string, toolCalls []openai.ChatCompletionMessageT
 	return blocks
 }
 
+// Export converts a conversation (e.g. a memory's message list) to OpenAI chat format, including tool calls and tool results.
+// The result can be used to build fine-tuning datasets or to hand the conversation over to other OpenAI tooling.
+func Export(msgs []agent.Message) ([]openai.ChatCompletionMessageParamUnion, error) {
+	var result []openai.ChatCompletionMessageParamUnion
+
+	// OpenAI does not accept attachments in tool messages, they are sent in a user message after the tool results
+	var attachments []agent.Attachment
+
+	for _, msg := range msgs {
+		_, isResult := msg.(agent.ToolResult)
+		_, isError := msg.(agent.ToolError)
+
+		if !isResult && !isError && len(attachments) > 0 {
+			result = append(result, attachmentsToOpenAI(attachments))
+			attachments = nil
+		}
+
+		m, err := messageToOpenAI(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, m)
+
+		if r, ok := msg.(agent.ToolResult); ok {
+			attachments = append(attachments, r.Attachments...)
+		}
+	}
+
+	if len(attachments) > 0 {
+		result = append(result, attachmentsToOpenAI(attachments))
+	}
+
+	return result, nil
+}
+
 // messageToOpenAI converts a universal Message to OpenAI-specific message format.
-func messageToOpenAI(msg agent.Message) openai.ChatCompletionMessageParamUnion {
+func messageToOpenAI(msg agent.Message) (openai.ChatCompletionMessageParamUnion, error) {
 	switch m := msg.(type) {
 	case agent.SystemMessage:
-		return systemMessageToOpenAI(m)
+		return systemMessageToOpenAI(m), nil
+	case agent.DeveloperMessage:
+		return developerMessageToOpenAI(m), nil
 	case agent.UserMessage:
-		return userMessageToOpenAI(m)
+		return userMessageToOpenAI(m), nil
 	case agent.AssistantMessage:
-		return assistantMessageToOpenAI(m)
+		return assistantMessageToOpenAI(m), nil
 	case agent.ToolResult:
-		return toolResultToOpenAI(m)
+		return toolResultToOpenAI(m), nil
 	case agent.ToolError:
-		return toolErrorToOpenAI(m)
+		return toolErrorToOpenAI(m), nil
 	default:
-		panic(fmt.Sprintf("unknown message type: %T", msg))
+		return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("unknown message type: %T", msg)
 	}
 }
 
@@ -314,6 +509,13 @@ func systemMessageToOpenAI(m agent.SystemMessage) openai.ChatCompletionMessagePa
 	}}
 }
 
+// developerMessageToOpenAI converts a DeveloperMessage to OpenAI format.
+func developerMessageToOpenAI(m agent.DeveloperMessage) openai.ChatCompletionMessageParamUnion {
+	return openai.ChatCompletionMessageParamUnion{OfDeveloper: &openai.ChatCompletionDeveloperMessageParam{
+		Content: openai.ChatCompletionDeveloperMessageParamContentUnion{OfString: param.NewOpt(m.Content)},
+	}}
+}
+
 // userMessageToOpenAI converts a UserMessage to OpenAI format.
 func userMessageToOpenAI(m agent.UserMessage) openai.ChatCompletionMessageParamUnion {
 	return openai.ChatCompletionMessageParamUnion{OfUser: &openai.ChatCompletionUserMessageParam{
@@ -363,6 +565,29 @@ func toolResultToOpenAI(c agent.ToolResult) openai.ChatCompletionMessageParamUni
 	return openai.ToolMessage(c.String(), c.CallID)
 }
 
+// attachmentsToOpenAI converts attachments of tool results to a user message.
+func attachmentsToOpenAI(attachments []agent.Attachment) openai.ChatCompletionMessageParamUnion {
+	parts := []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart("Attachments of the tool results above:")}
+
+	for _, a := range attachments {
+		data := base64.StdEncoding.EncodeToString(a.Data)
+
+		switch a.Type {
+		case agent.AttachmentTypeImage:
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: "data:" + a.MediaType + ";base64," + data,
+			}))
+		default:
+			parts = append(parts, openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+				FileData: openai.String("data:" + a.MediaType + ";base64," + data),
+				Filename: openai.String(a.Name),
+			}))
+		}
+	}
+
+	return openai.UserMessage(parts)
+}
+
 // toolErrorToOpenAI converts a ToolError to OpenAI format.
 func toolErrorToOpenAI(c agent.ToolError) openai.ChatCompletionMessageParamUnion {
 	return openai.ToolMessage(c.String(), c.CallID)
@@ -383,12 +608,7 @@ func toOpenAITools(tools []agent.Tool) []openai.ChatCompletionToolParam {
 				panic(fmt.Errorf("tool %q input schema must be object", tool.Name))
 			}
 
-			fn.Parameters = openai.FunctionParameters{
-				"type":                 "object",
-				"properties":           tool.InputSchema.Properties,
-				"required":             tool.InputSchema.Required,
-				"additionalProperties": false,
-			}
+			fn.Parameters = toOpenAIParameters(tool)
 		}
 
 		result[i] = openai.ChatCompletionToolParam{Function: fn}
@@ -396,3 +616,77 @@ func toOpenAITools(tools []agent.Tool) []openai.ChatCompletionToolParam {
 
 	return result
 }
+
+// toOpenAIParameters converts the complete input schema of the tool (including nested schemas and constraints, such as
+// enum or minimum) to function parameters.
+func toOpenAIParameters(tool agent.Tool) openai.FunctionParameters {
+	data, err := json.Marshal(tool.InputSchema)
+	if err != nil {
+		panic(fmt.Errorf("tool %q input schema can not be serialized: %w", tool.Name, err))
+	}
+
+	params := openai.FunctionParameters{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		panic(fmt.Errorf("tool %q input schema can not be serialized: %w", tool.Name, err))
+	}
+
+	if _, ok := params["properties"]; !ok {
+		params["properties"] = map[string]any{}
+	}
+
+	if _, ok := params["additionalProperties"]; !ok {
+		params["additionalProperties"] = false
+	}
+
+	return params
+}
+
+// requestOptions returns per-request options, the SDK reuses them when it retries the request.
+func requestOptions(req agent.CompletionRequest) []option.RequestOption {
+	var opts []option.RequestOption
+	if req.IdempotencyKey != "" {
+		opts = append(opts, option.WithHeader("Idempotency-Key", req.IdempotencyKey))
+	}
+
+	for key, value := range req.Extra {
+		opts = append(opts, option.WithJSONSet(key, value))
+	}
+
+	return opts
+}
+
+// WithoutParams returns a client option removing the given parameters from JSON bodies of requests, it's meant for
+// OpenAI-compatible APIs which reject some of the parameters set by the completer. Requests without a body (e.g. listing
+// models in Ping) are sent as is, unlike with option.WithJSONDel which fails on them.
+func WithoutParams(keys ...string) option.RequestOption {
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if req.Body == nil || req.Body == http.NoBody {
+			return next(req)
+		}
+
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var body map[string]json.RawMessage
+		if json.Unmarshal(data, &body) == nil {
+			for _, key := range keys {
+				delete(body, key)
+			}
+
+			if data, err = json.Marshal(body); err != nil {
+				return nil, err
+			}
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+
+		return next(req)
+	})
+}
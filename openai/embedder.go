@@ -0,0 +1,50 @@
+package openai
+
+import (
+	"context"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Embedder implements agent.Embedder using OpenAI embeddings API.
+type Embedder struct {
+	client openai.Client
+	model  string
+}
+
+// NewEmbedder creates a new OpenAI-based embedder for the given model (e.g. text-embedding-3-small).
+// It accepts the same options as openai.NewClient.
+func NewEmbedder(model string, opts ...option.RequestOption) *Embedder {
+	return &Embedder{client: openai.NewClient(opts...), model: model}
+}
+
+// NewEmbedderWithClient creates a new OpenAI-based embedder with an existing client.
+func NewEmbedderWithClient(client openai.Client, model string) *Embedder {
+	return &Embedder{client: client, model: model}
+}
+
+// Embed implements agent.Embedder by delegating to the OpenAI client.
+func (e *Embedder) Embed(ctx context.Context, texts ...string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	resp, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: e.model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range resp.Data {
+		if int(item.Index) < len(vectors) {
+			vectors[item.Index] = item.Embedding
+		}
+	}
+
+	return vectors, nil
+}
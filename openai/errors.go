@@ -0,0 +1,28 @@
+package openai
+
+import (
+	"errors"
+
+	"github.com/eolymp/go-agent"
+	"github.com/openai/openai-go"
+)
+
+// wrapError converts OpenAI SDK error to agent.APIError, other errors are returned as is.
+func wrapError(err error) error {
+	var e *openai.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	code := e.Code
+	if code == "" {
+		code = e.Type
+	}
+
+	message := e.Message
+	if message == "" {
+		message = e.Error()
+	}
+
+	return agent.NewAPIError(e.StatusCode, code, message, err)
+}
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"sync"
+
+	"github.com/eolymp/go-agent"
+	"github.com/pkoukk/tiktoken-go"
+	loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+var initLoader sync.Once
+
+// TokenCounter implements agent.TokenCounter using tiktoken encodings of OpenAI models.
+// Counting follows OpenAI guidance: every message takes 3 tokens of overhead and every reply is primed with 3 tokens.
+type TokenCounter struct {
+	lock      sync.Mutex
+	encodings map[string]*tiktoken.Tiktoken
+}
+
+// NewTokenCounter creates a new tiktoken-based token counter.
+// Encodings are embedded into the binary, so no network requests are made.
+func NewTokenCounter() *TokenCounter {
+	initLoader.Do(func() {
+		tiktoken.SetBpeLoader(loader.NewOfflineLoader())
+	})
+
+	return &TokenCounter{encodings: map[string]*tiktoken.Tiktoken{}}
+}
+
+// CountMessages implements agent.TokenCounter, unknown models are counted using o200k_base encoding.
+func (c *TokenCounter) CountMessages(model string, msgs []agent.Message) (int, error) {
+	enc, err := c.encoding(model)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 3 // every reply is primed with <|start|>assistant<|message|>
+	for _, msg := range msgs {
+		total += 3
+
+		switch m := msg.(type) {
+		case agent.SystemMessage:
+			total += len(enc.EncodeOrdinary(m.Content))
+		case agent.DeveloperMessage:
+			total += len(enc.EncodeOrdinary(m.Content))
+		case agent.UserMessage:
+			total += len(enc.EncodeOrdinary(m.Content))
+		case agent.AssistantMessage:
+			for _, block := range m.Content {
+				switch block.Type {
+				case agent.MessageBlockTypeText:
+					total += len(enc.EncodeOrdinary(block.Text))
+				case agent.MessageBlockTypeToolCall:
+					total += len(enc.EncodeOrdinary(block.ToolCall.Name)) + len(enc.EncodeOrdinary(block.ToolCall.Arguments))
+				}
+			}
+		case agent.ToolResult:
+			total += len(enc.EncodeOrdinary(m.String()))
+		case agent.ToolError:
+			total += len(enc.EncodeOrdinary(m.String()))
+		}
+	}
+
+	return total, nil
+}
+
+func (c *TokenCounter) encoding(model string) (*tiktoken.Tiktoken, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if enc, ok := c.encodings[model]; ok {
+		return enc, nil
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		enc, err = tiktoken.GetEncoding(tiktoken.MODEL_O200K_BASE)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.encodings[model] = enc
+	return enc, nil
+}
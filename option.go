@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
 type Option func(*Agent)
@@ -24,6 +28,40 @@ func WithToolset(tools Toolset) Option {
 	}
 }
 
+// WithEnabledTools restricts tools available to the model to the given list, other tools are hidden and calls to them are rejected.
+func WithEnabledTools(names ...string) Option {
+	return func(a *Agent) {
+		if a.enabled == nil {
+			a.enabled = make(map[string]bool, len(names))
+		}
+
+		for _, name := range names {
+			a.enabled[name] = true
+		}
+	}
+}
+
+// WithDisabledTools hides given tools from the model and rejects calls to them.
+func WithDisabledTools(names ...string) Option {
+	return func(a *Agent) {
+		if a.disabled == nil {
+			a.disabled = make(map[string]bool, len(names))
+		}
+
+		for _, name := range names {
+			a.disabled[name] = true
+		}
+	}
+}
+
+// WithToolFilter hides tools for which the filter returns false (e.g. tools the user has no permission to use), the
+// filter is called with the context of the run. Calls of hidden tools are rejected.
+func WithToolFilter(filter func(ctx context.Context, tool Tool) bool) Option {
+	return func(a *Agent) {
+		a.filters = append(a.filters, filter)
+	}
+}
+
 func WithModel(model string) Option {
 	return func(a *Agent) {
 		a.model = model
@@ -42,26 +80,54 @@ func WithChatCompleter(completer ChatCompleter) Option {
 	}
 }
 
-// SystemPrompt is for backwards compatibility
-// Deprecated, use WithSystemMessage instead
+// SystemPrompt is for backwards compatibility.
+//
+// Deprecated: use WithSystemMessage instead.
 func SystemPrompt(text string) Option {
 	return func(a *Agent) {
 		a.messages = append(a.messages, SystemMessage{Content: text})
 	}
 }
 
+// WithSystemMessage adds a system message to the starter messages of the agent. Starter messages are rendered with
+// template values and sent before the messages from memory, they are not stored in memory. Messages added by option
+// loaders (e.g. a prompt loaded from Braintrust) are appended after the ones added when the agent is created.
 func WithSystemMessage(text string) Option {
 	return func(a *Agent) {
 		a.messages = append(a.messages, SystemMessage{Content: text})
 	}
 }
 
+// WithSystemPrefix prepends text (e.g. a shared preamble) to the system message of the agent, whichever option or
+// prompt loader provides it. The text is rendered with the same template values as the system message.
+func WithSystemPrefix(text string) Option {
+	return func(a *Agent) {
+		a.sysPrefix = append(a.sysPrefix, text)
+	}
+}
+
+// WithSystemSuffix appends text to the system message of the agent, see WithSystemPrefix.
+func WithSystemSuffix(text string) Option {
+	return func(a *Agent) {
+		a.sysSuffix = append(a.sysSuffix, text)
+	}
+}
+
+// WithDeveloperMessage adds developer instructions, see DeveloperMessage.
+func WithDeveloperMessage(text string) Option {
+	return func(a *Agent) {
+		a.messages = append(a.messages, DeveloperMessage{Content: text})
+	}
+}
+
+// WithUserMessage adds a user message to the starter messages of the agent, see WithSystemMessage.
 func WithUserMessage(text string) Option {
 	return func(a *Agent) {
 		a.messages = append(a.messages, UserMessage{Content: text})
 	}
 }
 
+// WithAssistantMessage adds an assistant message to the starter messages of the agent, see WithSystemMessage.
 func WithAssistantMessage(text string) Option {
 	return func(a *Agent) {
 		a.messages = append(a.messages, AssistantMessage{
@@ -94,6 +160,31 @@ func WithValues(values map[string]any) Option {
 	}
 }
 
+// WithoutReservedValues disables reserved template values: date, time and datetime (also available as {{_sys.date}},
+// {{_sys.time}} and {{_sys.datetime}}). Reserved values never overwrite values with the same name given by the user,
+// disable them to keep templates fully deterministic.
+func WithoutReservedValues() Option {
+	return func(a *Agent) {
+		a.noReserved = true
+	}
+}
+
+// WithDefaultValues sets default values for template substitution, values set with WithValues take precedence over
+// them regardless of the order of options. It's used to apply defaults defined by loaded prompts.
+func WithDefaultValues(values map[string]any) Option {
+	return func(a *Agent) {
+		if a.defaults == nil {
+			a.defaults = make(map[string]any, len(values))
+		}
+
+		for k, v := range values {
+			a.defaults[k] = v
+		}
+	}
+}
+
+// WithStructuredOutput requires the final reply to be a JSON value, the reply is validated and the model is asked to
+// fix it if it's not a valid JSON. Response format is not sent to the provider, use WithJSONSchema for that.
 func WithStructuredOutput() Option {
 	return func(a *Agent) {
 		a.finalizer = append(a.finalizer, func(reply *AssistantMessage) error {
@@ -109,6 +200,60 @@ func WithStructuredOutput() Option {
 	}
 }
 
+// WithJSONSchema requires the final reply to be a JSON value matching the schema. If the reply does not match, the
+// model is asked to fix it, the validation error pointing to the failing field is fed back to the model.
+func WithJSONSchema(schema *jsonschema.Schema) Option {
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		panic(fmt.Errorf("invalid JSON schema: %w", err))
+	}
+
+	return func(a *Agent) {
+		a.format = &ResponseFormat{Name: formatName(schema.Title), Schema: schema}
+		a.finalizer = append(a.finalizer, func(reply *AssistantMessage) error {
+			text := strings.TrimSpace(reply.Text())
+			text = strings.TrimPrefix(strings.Trim(text, "`"), "json")
+
+			var value any
+			if err := json.Unmarshal([]byte(text), &value); err != nil {
+				return fmt.Errorf("response must be a valid JSON: %w", err)
+			}
+
+			if err := resolved.Validate(value); err != nil {
+				return fmt.Errorf("response does not match the schema: %w. Fix the field and respond with the corrected JSON only", err)
+			}
+
+			return nil
+		})
+	}
+}
+
+// formatName converts the schema title to a name accepted by providers: letters, digits, underscores and dashes, up to
+// 64 characters. It returns "response" if the title has no valid characters.
+func formatName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r == ' ' || r == '.':
+			return '_'
+		default:
+			return -1
+		}
+	}, title)
+
+	name = strings.Trim(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	if name == "" {
+		return "response"
+	}
+
+	return name
+}
+
 func WithOptions(opts ...Option) Option {
 	return func(a *Agent) {
 		for _, opt := range opts {
@@ -123,18 +268,242 @@ func WithModelMapper(mapping map[string]string) Option {
 	}
 }
 
+// WithModelFallback retries a completion with fallback models (in the given order) when the primary model is
+// overloaded or unavailable (rate limited, 503 or 529 responses of the provider). Model names are resolved with the
+// model mapping, max tokens are clamped to the limit of the fallback model, and the cost is computed with the price of
+// the model which answered.
+func WithModelFallback(primary string, fallbacks ...string) Option {
+	return func(a *Agent) {
+		if a.fallbacks == nil {
+			a.fallbacks = map[string][]string{}
+		}
+
+		a.fallbacks[primary] = append([]string(nil), fallbacks...)
+	}
+}
+
 func WithFinalizer(ff ...func(*AssistantMessage) error) Option {
 	return func(a *Agent) {
 		a.finalizer = append(a.finalizer, ff...)
 	}
 }
 
+// WithExtraMessages adds messages (e.g. retrieved documents or current state) to every request of the run, after the
+// messages from memory. The messages are not stored in memory, use it as a run option to provide transient context.
+func WithExtraMessages(msgs ...Message) Option {
+	return func(a *Agent) {
+		a.ephemeral = append(a.ephemeral, msgs...)
+	}
+}
+
+// WithMessagePreprocessor adds a function to transform messages right before they are sent to the model (e.g. to trim
+// old tool results or redact sensitive data). Preprocessors run in the order they are added and receive a copy of
+// the message list, stored memory is not affected. Messages must be replaced rather than modified in place.
+func WithMessagePreprocessor(pp ...func(messages []Message) []Message) Option {
+	return func(a *Agent) {
+		a.preprocess = append(a.preprocess, pp...)
+	}
+}
+
+// WithMemoryLimit limits the number of messages sent to the model to system messages and the most recent maxMessages
+// messages. It's a simple alternative to token based trimming, stored memory is not affected. The limit must be positive.
+func WithMemoryLimit(maxMessages int) Option {
+	if maxMessages < 1 {
+		panic("memory limit must be positive")
+	}
+
+	return WithMessagePreprocessor(func(messages []Message) []Message {
+		return limitMessages(messages, maxMessages)
+	})
+}
+
+// WithToolResultRetention replaces results of tool calls made before the last turns user messages with a short
+// placeholder in requests to the model, so large payloads of earlier turns do not bloat the context. Tool calls are
+// kept to keep the conversation coherent, stored memory is not affected. Turns must be at least 1 (the current turn).
+func WithToolResultRetention(turns int) Option {
+	if turns < 1 {
+		panic("tool result retention must be at least 1 turn")
+	}
+
+	return WithMessagePreprocessor(func(messages []Message) []Message {
+		return retainToolResults(messages, turns)
+	})
+}
+
+// WithDedupeMessages drops consecutive identical messages (e.g. system prompt doubled after handoff) from requests to
+// the model. User messages are never dropped, as users may repeat themselves. Stored memory is not affected.
+func WithDedupeMessages() Option {
+	return WithMessagePreprocessor(dedupeMessages)
+}
+
+// WithTraceProject sends spans of the agent run (including nested runs) to the given tracing project instead of the
+// project configured in the tracer.
+func WithTraceProject(project string) Option {
+	return func(a *Agent) {
+		a.traceProj = project
+	}
+}
+
+// WithRunID sets ID of the run used to correlate logs, traces and results (e.g. with ID of the user request). By
+// default runs started by tools (e.g. specialists) inherit ID of the calling run, and a random ID is generated for
+// other runs. The ID is recorded in the span of the run, is available to tools
+// with RunIDFromContext and is set in replies of the model. Use it as an option of Run.
+func WithRunID(id string) Option {
+	return func(a *Agent) {
+		a.runID = id
+	}
+}
+
+// WithTraceTags attaches tags (e.g. tenant ID or experiment name) to the agent span and all spans started during the run.
+func WithTraceTags(tags ...string) Option {
+	return func(a *Agent) {
+		a.traceTags = append(a.traceTags, tags...)
+	}
+}
+
+// WithMaxCost limits the cost of a single run in USD. Cost of every completion is calculated from its usage using
+// given pricing, the run is stopped with ErrBudgetExceeded before the next completion if it's expected to exceed the
+// budget (the next completion is assumed to cost at least as much as the previous one). Completions of models missing
+// in pricing are not counted.
+func WithMaxCost(usd float64, pricing Pricing) Option {
+	return func(a *Agent) {
+		a.maxCost = usd
+		a.pricing = pricing
+	}
+}
+
+// WithObserver adds a function called synchronously after every iteration of the agentic loop with the request,
+// response and results of tool calls. Observers must not modify the event, it's intended for debugging and custom UIs.
+func WithObserver(oo ...Observer) Option {
+	return func(a *Agent) {
+		a.observers = append(a.observers, oo...)
+	}
+}
+
+// StopCondition tells if the run should end, it's called with the last reply of the model and memory of the run.
+type StopCondition func(ctx context.Context, reply *AssistantMessage, memory Memory) (bool, error)
+
+// WithStopCondition ends the run when the condition is met (e.g. a tool has written the expected file). Conditions are
+// evaluated after tool calls of every iteration, the run returns the last reply of the model without finalizers. If
+// the condition fails, the run fails with its error.
+func WithStopCondition(cc ...StopCondition) Option {
+	return func(a *Agent) {
+		a.stops = append(a.stops, cc...)
+	}
+}
+
+// WithToolRetry retries calls of the tool which fail with a temporary error (an error implementing
+// `Temporary() bool` and returning true), so the model does not need to spend a round-trip to retry it.
+// The error is reported to the model as usual once all attempts are exhausted.
+func WithToolRetry(name string, attempts int, backoff time.Duration) Option {
+	return func(a *Agent) {
+		if a.retries == nil {
+			a.retries = map[string]ToolRetry{}
+		}
+
+		a.retries[name] = ToolRetry{Attempts: attempts, Backoff: backoff}
+	}
+}
+
+// WithToolContext adds a value to the context passed to tools, use it to provide shared dependencies (e.g. database
+// handle or tenant ID) to tools without closing over them. The value can be retrieved with ToolValue or ctx.Value.
+func WithToolContext(key, value any) Option {
+	return func(a *Agent) {
+		if a.toolValues == nil {
+			a.toolValues = map[any]any{}
+		}
+
+		a.toolValues[key] = value
+	}
+}
+
+// WithToolErrorFormatter sets a function which formats tool errors presented to the model (e.g. as JSON with code,
+// message and hint), by default errors are presented as "ERROR: " followed by the error message.
+func WithToolErrorFormatter(format func(name string, err error) string) Option {
+	return func(a *Agent) {
+		a.errFormat = format
+	}
+}
+
+// ToolResultTransformer transforms the result returned by the tool with the given name, see WithToolResultTransformer.
+type ToolResultTransformer func(ctx context.Context, name string, result any) any
+
+// WithToolResultTransformer post-processes results of all tools (e.g. to redact secrets or reformat them) before they
+// are recorded in the trace and added to memory. The result may be a ToolOutput if the tool returned attachments.
+// Transformers run in the order they are added, errors are formatted with WithToolErrorFormatter instead.
+func WithToolResultTransformer(tt ...ToolResultTransformer) Option {
+	return func(a *Agent) {
+		a.transforms = append(a.transforms, tt...)
+	}
+}
+
+// WithToolTimeout limits the duration of every tool call (every attempt, if the call is retried), the context of the
+// tool is cancelled when the timeout expires and the timeout is reported to the model as the tool error. Tools must
+// respect the context, otherwise they keep running after the timeout.
+func WithToolTimeout(timeout time.Duration) Option {
+	return func(a *Agent) {
+		a.toolTimeout = timeout
+	}
+}
+
+// WithMaxToolResultBytes limits the size of tool results (and errors) added to memory, larger results are truncated
+// and marked with `...[truncated N bytes]`, so a single tool call can't exhaust the context window.
+func WithMaxToolResultBytes(n int) Option {
+	return func(a *Agent) {
+		a.maxResult = n
+	}
+}
+
 func WithApprover(aa ...func(call ToolCall) ToolCallApproval) Option {
 	return func(a *Agent) {
 		a.approver = append(a.approver, aa...)
 	}
 }
 
+// WithLoopDetection detects the model calling the same tool with the same arguments in repeats turns in a row and either
+// asks the model to change approach (LoopActionNudge) or stops the run with ErrStuckLoop (LoopActionAbort). Identical
+// calls made in parallel within one turn are counted once.
+func WithLoopDetection(repeats int, action LoopAction) Option {
+	return func(a *Agent) {
+		a.loopLimit = repeats
+		a.loopAction = action
+	}
+}
+
+// WithContinueOnLength asks the model to continue when reply is cut off by max tokens limit, pieces of the reply are stitched together.
+// The number of continuations is limited to avoid runaway cost. Replies with tool calls are not continued, ErrTruncatedToolCalls
+// is returned for them instead.
+func WithContinueOnLength(maxContinuations int) Option {
+	return func(a *Agent) {
+		a.continues = maxContinuations
+	}
+}
+
+// WithParallelToolCalls allows or forbids the model to request several tool calls at once. When disabled, tool calls
+// are also executed one by one, in the order they were requested. By default, parallel tool calls are allowed unless
+// tool parallelism is limited to 1.
+func WithParallelToolCalls(enabled bool) Option {
+	return func(a *Agent) {
+		a.parallel = &enabled
+	}
+}
+
+// WithMaxConcurrentAgents limits the number of agent runs waiting for the model at the same time, including nested runs
+// started by tools (e.g. specialists or orchestrated tasks), to avoid exhausting rate limits of the provider. The limit
+// is shared by all agents configured with the same option and by runs nested into them. A run holds the slot only while
+// it waits for the completion, so nested runs can't deadlock waiting for their parents.
+func WithMaxConcurrentAgents(n int) Option {
+	if n < 1 {
+		panic("max concurrent agents must be positive")
+	}
+
+	sem := make(chan struct{}, n)
+
+	return func(a *Agent) {
+		a.semaphore = sem
+	}
+}
+
 func WithToolParallelism(limit int) Option {
 	return func(a *Agent) {
 		a.parallelism = limit
@@ -159,18 +528,90 @@ func WithReasoning(config *Reasoning) Option {
 	}
 }
 
+// minReasoningBudget is the smallest budget of thinking tokens accepted by Anthropic.
+const minReasoningBudget = 1024
+
+// WithReasoningBudget enables reasoning with the given budget of thinking tokens (Anthropic specific, at least 1024),
+// the budget must be less than max tokens.
+func WithReasoningBudget(tokens int) Option {
+	return func(a *Agent) {
+		reasoning := Reasoning{}
+		if a.reasoning != nil {
+			reasoning = *a.reasoning
+		}
+
+		reasoning.Enabled = true
+		reasoning.Budget = tokens
+		a.reasoning = &reasoning
+	}
+}
+
+// WithReasoningEffort enables reasoning with the given effort: "low", "medium" or "high". OpenAI uses it as reasoning
+// effort, Anthropic derives the budget of thinking tokens from it unless the budget is set explicitly.
+func WithReasoningEffort(level string) Option {
+	return func(a *Agent) {
+		reasoning := Reasoning{}
+		if a.reasoning != nil {
+			reasoning = *a.reasoning
+		}
+
+		reasoning.Enabled = true
+		reasoning.Effort = level
+		a.reasoning = &reasoning
+	}
+}
+
 func WithTemperature(temperature float32) Option {
 	return func(a *Agent) {
 		a.temperature = &temperature
 	}
 }
 
+// WithDeterministic makes completions as reproducible as possible: temperature is set to 0, top_p to 1 and sampling
+// seed to the given value (where supported). Providers do not guarantee identical results even with these settings.
+func WithDeterministic(seed int) Option {
+	return func(a *Agent) {
+		temperature, topP, value := float32(0), float32(1), int64(seed)
+
+		a.temperature = &temperature
+		a.topP = &topP
+		a.seed = &value
+	}
+}
+
+// WithMaxTokens sets the max number of output tokens, the value is clamped to the limit of the model if it's known
+// (see SetModelMaxTokens).
 func WithMaxTokens(maxTokens int64) Option {
 	return func(a *Agent) {
 		a.maxTokens = &maxTokens
 	}
 }
 
+// WithLogProbs requests log probabilities of output tokens with top most likely alternatives of each token, they are
+// available in CompletionResponse passed to observers (see WithObserver). Only OpenAI compatible providers support it.
+func WithLogProbs(top int) Option {
+	return func(a *Agent) {
+		a.logProbs = true
+		a.topLogProbs = top
+	}
+}
+
+// WithRawCompletion sets provider specific parameters of completion requests which are not modeled by
+// CompletionRequest. Keys are set as fields of the request body overriding values set by the completer, OpenAI and
+// Anthropic completers accept dotted paths (e.g. "metadata.user_id"). Examples: "logprobs", "top_logprobs",
+// "service_tier" (OpenAI), "metadata.user_id" (Anthropic), "seed", "safety_mode" (Cohere).
+func WithRawCompletion(extra map[string]any) Option {
+	return func(a *Agent) {
+		if a.extra == nil {
+			a.extra = map[string]any{}
+		}
+
+		for k, v := range extra {
+			a.extra[k] = v
+		}
+	}
+}
+
 func WithTopP(topP float32) Option {
 	return func(a *Agent) {
 		a.topP = &topP
@@ -183,6 +624,15 @@ func WithTopK(topK int32) Option {
 	}
 }
 
+// WithCachedPrefix marks the first n messages of the request (system prompt and messages which do not change between
+// requests) as a cacheable prefix. Anthropic caches the prefix with an explicit cache breakpoint, OpenAI caches prompt
+// prefixes automatically. Effect of caching is reported in CompletionUsage.CachedPromptTokens.
+func WithCachedPrefix(n int) Option {
+	return func(a *Agent) {
+		a.cachePrefix = n
+	}
+}
+
 func WithoutCache() Option {
 	no := false
 	return func(a *Agent) {
@@ -197,13 +647,40 @@ func WithApprovals(calls ...string) Option {
 		m[call] = true
 	}
 
-	return WithApprover(func(call ToolCall) ToolCallApproval {
+	approver := WithApprover(func(call ToolCall) ToolCallApproval {
 		if m[call.ID] {
 			return ToolCallApproved
 		}
 
 		return ToolCallUndecided
 	})
+
+	return func(a *Agent) {
+		approver(a)
+
+		if a.approvals == nil {
+			a.approvals = map[string]bool{}
+		}
+
+		for _, call := range calls {
+			a.approvals[call] = true
+		}
+	}
+}
+
+// WithApprovalRequiredTags protects tools tagged with any of the given tags (e.g. "destructive"): their calls are not
+// approved automatically (e.g. by WithAutoApproveAll or WithAutoApproveTools), only calls approved explicitly with
+// WithApprovals are executed.
+func WithApprovalRequiredTags(tags ...string) Option {
+	return func(a *Agent) {
+		if a.protected == nil {
+			a.protected = map[string]bool{}
+		}
+
+		for _, tag := range tags {
+			a.protected[tag] = true
+		}
+	}
 }
 
 // WithRejections creates approver which rejects specific calls
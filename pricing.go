@@ -0,0 +1,33 @@
+package agent
+
+// ModelPrice defines the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Prompt       float64 // price of prompt (input) tokens
+	Completion   float64 // price of completion (output) tokens
+	CachedPrompt float64 // price of prompt tokens read from cache, charged in addition to prompt tokens
+}
+
+// Pricing maps model names to their prices. Models are matched by exact name first, then by the longest prefix,
+// so "gpt-4o" matches "gpt-4o-2024-08-06".
+//
+// Providers report cached tokens differently: OpenAI includes them in prompt tokens, while Anthropic reports them
+// separately. Set CachedPrompt price accordingly (e.g. to zero for OpenAI models to avoid double counting).
+type Pricing map[string]ModelPrice
+
+// Cost calculates the cost of the completion in USD, it returns false if the model has no price.
+func (p Pricing) Cost(model string, usage CompletionUsage) (float64, bool) {
+	price, ok := p.lookup(model)
+	if !ok {
+		return 0, false
+	}
+
+	cost := float64(usage.PromptTokens)*price.Prompt +
+		float64(usage.CompletionTokens)*price.Completion +
+		float64(usage.CachedPromptTokens)*price.CachedPrompt
+
+	return cost / 1_000_000, true
+}
+
+func (p Pricing) lookup(model string) (ModelPrice, bool) {
+	return lookupModel(p, model)
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// SchemaProperty is a named property of an object schema, see SchemaObject.
+type SchemaProperty struct {
+	Name     string
+	Schema   *jsonschema.Schema
+	Required bool
+}
+
+// Required defines a property which must be present in the object.
+func Required(name string, schema *jsonschema.Schema) SchemaProperty {
+	return SchemaProperty{Name: name, Schema: schema, Required: true}
+}
+
+// Optional defines a property which may be omitted.
+func Optional(name string, schema *jsonschema.Schema) SchemaProperty {
+	return SchemaProperty{Name: name, Schema: schema}
+}
+
+// SchemaObject creates an object schema with the given properties. Additional properties are not allowed, and
+// properties are rendered in the order they are given.
+func SchemaObject(props ...SchemaProperty) *jsonschema.Schema {
+	s := &jsonschema.Schema{
+		Type:                 "object",
+		AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
+		Properties:           make(map[string]*jsonschema.Schema, len(props)),
+	}
+
+	for _, prop := range props {
+		s.Properties[prop.Name] = prop.Schema
+		s.PropertyOrder = append(s.PropertyOrder, prop.Name)
+
+		if prop.Required {
+			s.Required = append(s.Required, prop.Name)
+		}
+	}
+
+	return s
+}
+
+// SchemaString creates a string schema with the given description.
+func SchemaString(desc string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "string", Description: desc}
+}
+
+// SchemaEnum creates a string schema which accepts only the given values.
+func SchemaEnum(desc string, values ...string) *jsonschema.Schema {
+	enum := make([]any, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+
+	return &jsonschema.Schema{Type: "string", Description: desc, Enum: enum}
+}
+
+// SchemaInteger creates an integer schema with the given description.
+func SchemaInteger(desc string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "integer", Description: desc}
+}
+
+// SchemaNumber creates a number schema with the given description.
+func SchemaNumber(desc string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "number", Description: desc}
+}
+
+// SchemaBoolean creates a boolean schema with the given description.
+func SchemaBoolean(desc string) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "boolean", Description: desc}
+}
+
+// SchemaArray creates an array schema with items matching the given schema.
+func SchemaArray(desc string, items *jsonschema.Schema) *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "array", Description: desc, Items: items}
+}
@@ -1,20 +1,28 @@
 package agent
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 type Streamer interface {
 	Stream(ctx context.Context, chunk Chunk) error
 }
 
+// Chunk is a piece of a streamed completion.
+//
+// Completers report usage with StreamChunkTypeUsage chunks, each of them carries cumulative usage of the completion so
+// far (not an increment), so consumers may simply keep the latest one. The stream ends with exactly one
+// StreamChunkTypeFinish chunk, which carries the final FinishReason and the final usage.
 type Chunk struct {
-	Type         StreamChunkType
-	Index        int
-	Text         string      // For text and thinking deltas
-	Call         *ToolCall   // For tool calls (both user and server tools)
-	Signature    string      // For thinking signature
-	Result       *ToolResult // For inline tool results
-	Usage        *CompletionUsage
-	FinishReason FinishReason
+	Type         StreamChunkType  `json:"type"`
+	Index        int              `json:"index"`
+	Text         string           `json:"text,omitempty"`      // For text and thinking deltas
+	Call         *ToolCall        `json:"call,omitempty"`      // For tool calls (both user and server tools)
+	Signature    string           `json:"signature,omitempty"` // For thinking signature
+	Result       *ToolResult      `json:"result,omitempty"`    // For inline tool results
+	Usage        *CompletionUsage `json:"usage,omitempty"`
+	FinishReason FinishReason     `json:"finish_reason"`
 }
 
 type StreamChunkType int
@@ -30,8 +38,9 @@ const (
 	StreamChunkTypeServerToolCallStart                        // built-in tool call start (web_search, bash, etc.)
 	StreamChunkTypeServerToolCallDelta                        // built-in tool call arguments delta
 	StreamChunkTypeToolResult                                 // inline tool result from server
-	StreamChunkTypeUsage                                      // usage statistics update
-	StreamChunkTypeFinish                                     // the completion has finished
+	StreamChunkTypeUsage                                      // cumulative usage statistics of the completion so far
+	StreamChunkTypeFinish                                     // the completion has finished, carries final finish reason and usage
+	StreamChunkTypeToolCallProgress                           // progress output of a running tool (comes from agent, not LLM)
 )
 
 func (s StreamChunkType) String() string {
@@ -60,7 +69,43 @@ func (s StreamChunkType) String() string {
 		return "usage"
 	case StreamChunkTypeFinish:
 		return "finish"
+	case StreamChunkTypeToolCallProgress:
+		return "tool_call_progress"
 	default:
 		return "unknown"
 	}
 }
+
+func (s StreamChunkType) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *StreamChunkType) UnmarshalText(text []byte) error {
+	for t := StreamChunkTypeText; t <= StreamChunkTypeToolCallProgress; t++ {
+		if t.String() == string(text) {
+			*s = t
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown stream chunk type %q", string(text))
+}
+
+// ToolProgress reports progress of a long-running tool to the stream of the agent, the text is sent as
+// StreamChunkTypeToolCallProgress chunk with the call being executed. It does nothing if the agent is not streaming or
+// the function is not called from within a tool.
+func ToolProgress(ctx context.Context, text string) error {
+	s, ok := ctx.Value(contextToolStream).(toolStream)
+	if !ok {
+		return nil
+	}
+
+	return s.streamer.Stream(ctx, Chunk{Type: StreamChunkTypeToolCallProgress, Index: s.index, Text: text, Call: &ToolCall{ID: s.call.ID, Name: s.call.Name}})
+}
+
+// toolStream is a stream of a tool call being executed.
+type toolStream struct {
+	streamer Streamer
+	index    int
+	call     ToolCall
+}
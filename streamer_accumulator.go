@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"sort"
+)
+
+// ChunkAccumulator reconstructs the reply of the model from chunks of a streamed completion: text, reasoning with its
+// signature, tool calls and results of server tools. Chunks produced by the agent (e.g. tool call execution and
+// progress) are ignored. Use a new accumulator for every completion.
+type ChunkAccumulator struct {
+	blocks map[int]*MessageBlock
+	usage  CompletionUsage
+	reason FinishReason
+}
+
+func NewChunkAccumulator() *ChunkAccumulator {
+	return &ChunkAccumulator{blocks: map[int]*MessageBlock{}}
+}
+
+// Add consumes a chunk of the stream.
+func (a *ChunkAccumulator) Add(chunk Chunk) {
+	switch chunk.Type {
+	case StreamChunkTypeText:
+		a.block(chunk.Index, MessageBlockTypeText).Text += chunk.Text
+	case StreamChunkTypeReasoning:
+		a.block(chunk.Index, MessageBlockTypeReasoning).Text += chunk.Text
+	case StreamChunkTypeSignature:
+		a.block(chunk.Index, MessageBlockTypeSignature).Signature += chunk.Signature
+	case StreamChunkTypeToolCallStart, StreamChunkTypeToolCallDelta:
+		a.call(chunk, MessageBlockTypeToolCall)
+	case StreamChunkTypeServerToolCallStart, StreamChunkTypeServerToolCallDelta:
+		a.call(chunk, MessageBlockTypeServerToolCall)
+	case StreamChunkTypeToolResult:
+		if chunk.Result != nil {
+			result := *chunk.Result
+			a.block(chunk.Index, MessageBlockTypeToolResult).ToolResult = &result
+		}
+	case StreamChunkTypeUsage:
+		if chunk.Usage != nil {
+			a.usage = *chunk.Usage
+		}
+	case StreamChunkTypeFinish:
+		a.reason = chunk.FinishReason
+		if chunk.Usage != nil {
+			a.usage = *chunk.Usage
+		}
+	}
+}
+
+// Message returns the reply accumulated so far, blocks are ordered by their index in the stream.
+func (a *ChunkAccumulator) Message() AssistantMessage {
+	indexes := make([]int, 0, len(a.blocks))
+	for index := range a.blocks {
+		indexes = append(indexes, index)
+	}
+
+	sort.Ints(indexes)
+
+	content := make([]MessageBlock, len(indexes))
+	for i, index := range indexes {
+		content[i] = *a.blocks[index]
+
+		if call := content[i].ToolCall; call != nil {
+			c := *call
+			content[i].ToolCall = &c
+		}
+	}
+
+	return AssistantMessage{Content: content}
+}
+
+// Usage returns the latest usage reported in the stream.
+func (a *ChunkAccumulator) Usage() CompletionUsage {
+	return a.usage
+}
+
+// FinishReason returns the finish reason reported at the end of the stream.
+func (a *ChunkAccumulator) FinishReason() FinishReason {
+	return a.reason
+}
+
+// block returns the block at the index, it's created with the given type if it does not exist. Signature is attached
+// to the reasoning block it belongs to.
+func (a *ChunkAccumulator) block(index int, kind MessageBlockType) *MessageBlock {
+	b, ok := a.blocks[index]
+	if !ok {
+		b = &MessageBlock{Type: kind}
+		a.blocks[index] = b
+	}
+
+	return b
+}
+
+func (a *ChunkAccumulator) call(chunk Chunk, kind MessageBlockType) {
+	if chunk.Call == nil {
+		return
+	}
+
+	b := a.block(chunk.Index, kind)
+	if b.ToolCall == nil {
+		b.ToolCall = &ToolCall{ID: chunk.Call.ID, Name: chunk.Call.Name}
+	}
+
+	switch chunk.Type {
+	case StreamChunkTypeToolCallDelta, StreamChunkTypeServerToolCallDelta:
+		b.ToolCall.Arguments += chunk.Call.Arguments
+	}
+}
@@ -0,0 +1,46 @@
+package agent_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+)
+
+func TestChunkAccumulator(t *testing.T) {
+	chunks := []agent.Chunk{
+		{Type: agent.StreamChunkTypeReasoning, Index: 0, Text: "let me "},
+		{Type: agent.StreamChunkTypeReasoning, Index: 0, Text: "think"},
+		{Type: agent.StreamChunkTypeSignature, Index: 0, Signature: "sig"},
+		{Type: agent.StreamChunkTypeText, Index: 1, Text: "Hello, "},
+		{Type: agent.StreamChunkTypeText, Index: 1, Text: "world"},
+		{Type: agent.StreamChunkTypeToolCallStart, Index: 2, Call: &agent.ToolCall{ID: "call_1", Name: "search"}},
+		{Type: agent.StreamChunkTypeToolCallDelta, Index: 2, Call: &agent.ToolCall{ID: "call_1", Name: "search", Arguments: `{"query":`}},
+		{Type: agent.StreamChunkTypeToolCallDelta, Index: 2, Call: &agent.ToolCall{ID: "call_1", Name: "search", Arguments: `"go"}`}},
+		{Type: agent.StreamChunkTypeUsage, Usage: &agent.CompletionUsage{PromptTokens: 10}},
+		{Type: agent.StreamChunkTypeFinish, FinishReason: agent.FinishReasonToolCalls, Usage: &agent.CompletionUsage{PromptTokens: 10, CompletionTokens: 5}},
+	}
+
+	acc := agent.NewChunkAccumulator()
+	for _, chunk := range chunks {
+		acc.Add(chunk)
+	}
+
+	want := agent.AssistantMessage{Content: []agent.MessageBlock{
+		{Type: agent.MessageBlockTypeReasoning, Text: "let me think", Signature: "sig"},
+		{Type: agent.MessageBlockTypeText, Text: "Hello, world"},
+		{Type: agent.MessageBlockTypeToolCall, ToolCall: &agent.ToolCall{ID: "call_1", Name: "search", Arguments: `{"query":"go"}`}},
+	}}
+
+	if got := acc.Message(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Message() = %+v, want %+v", got, want)
+	}
+
+	if got := acc.FinishReason(); got != agent.FinishReasonToolCalls {
+		t.Errorf("FinishReason() = %v, want %v", got, agent.FinishReasonToolCalls)
+	}
+
+	if got := acc.Usage(); got.CompletionTokens != 5 {
+		t.Errorf("Usage().CompletionTokens = %d, want 5", got.CompletionTokens)
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolArguments accumulates tool call arguments streamed in StreamChunkTypeToolCallDelta chunks, so they can be
+// rendered while the model is still writing them. Calls are tracked by call ID (or chunk index if ID is missing).
+type ToolArguments struct {
+	args map[string]*strings.Builder
+}
+
+func NewToolArguments() *ToolArguments {
+	return &ToolArguments{args: map[string]*strings.Builder{}}
+}
+
+// Add consumes a chunk and returns arguments of the tool call it belongs to. For delta chunks arguments are parsed
+// on a best-effort basis: incomplete values are closed or omitted. StreamChunkTypeToolCallComplete chunk returns
+// fully parsed arguments or an error if they are not a valid JSON. Other chunks return nil.
+func (t *ToolArguments) Add(chunk Chunk) (map[string]any, error) {
+	if chunk.Call == nil {
+		return nil, nil
+	}
+
+	key := chunk.Call.ID
+	if key == "" {
+		key = fmt.Sprint(chunk.Index)
+	}
+
+	switch chunk.Type {
+	case StreamChunkTypeToolCallStart, StreamChunkTypeServerToolCallStart:
+		t.args[key] = &strings.Builder{}
+		return map[string]any{}, nil
+	case StreamChunkTypeToolCallDelta, StreamChunkTypeServerToolCallDelta:
+		b, ok := t.args[key]
+		if !ok {
+			b = &strings.Builder{}
+			t.args[key] = b
+		}
+
+		b.WriteString(chunk.Call.Arguments)
+
+		args, _ := ParsePartialJSON(b.String())
+		return args, nil
+	case StreamChunkTypeToolCallComplete:
+		b, ok := t.args[key]
+		if !ok {
+			return nil, nil
+		}
+
+		delete(t.args, key)
+
+		args := map[string]any{}
+		if text := strings.TrimSpace(b.String()); text != "" && text != "null" {
+			if err := json.Unmarshal([]byte(text), &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments of tool call %q: %w", chunk.Call.Name, err)
+			}
+		}
+
+		return args, nil
+	default:
+		return nil, nil
+	}
+}
+
+// ParsePartialJSON parses a prefix of JSON object: unterminated strings, objects and arrays are closed, incomplete
+// keys and values are omitted. It returns false if nothing could be parsed.
+func ParsePartialJSON(text string) (map[string]any, bool) {
+	var value map[string]any
+	if err := json.Unmarshal([]byte(text), &value); err == nil {
+		return value, true
+	}
+
+	for cut := len(text); cut > 0; {
+		if candidate, ok := closeJSON(text[:cut]); ok {
+			value = nil
+			if err := json.Unmarshal([]byte(candidate), &value); err == nil && value != nil {
+				return value, true
+			}
+		}
+
+		// back off to the previous position where a value starts
+		i := strings.LastIndexAny(text[:cut-1], "{[,")
+		if i < 0 {
+			break
+		}
+
+		cut = i + 1
+	}
+
+	return nil, false
+}
+
+// closeJSON completes a JSON prefix by closing open string, arrays and objects.
+func closeJSON(text string) (string, bool) {
+	var stack []byte
+	var str, escape bool
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		if str {
+			switch {
+			case escape:
+				escape = false
+			case c == '\\':
+				escape = true
+			case c == '"':
+				str = false
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"':
+			str = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return "", false
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if str {
+		// drop incomplete escape sequence
+		if escape {
+			text = text[:len(text)-1]
+		} else if i := strings.LastIndex(text, `\u`); i >= 0 && len(text)-i < 6 && !strings.HasSuffix(text[:i], `\`) {
+			text = text[:i]
+		}
+
+		text += `"`
+	}
+
+	text = strings.TrimRight(text, " \t\r\n")
+	text = strings.TrimSuffix(text, ",")
+
+	if strings.HasSuffix(text, ":") {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(text)
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteByte(stack[i])
+	}
+
+	return b.String(), true
+}
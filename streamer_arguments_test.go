@@ -0,0 +1,76 @@
+package agent_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eolymp/go-agent"
+)
+
+func TestParsePartialJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  map[string]any
+		ok    bool
+	}{
+		{input: ``, ok: false},
+		{input: `{`, want: map[string]any{}, ok: true},
+		{input: `{"query": "hel`, want: map[string]any{"query": "hel"}, ok: true},
+		{input: `{"query": "hello", `, want: map[string]any{"query": "hello"}, ok: true},
+		{input: `{"query": "hello", "lim`, want: map[string]any{"query": "hello"}, ok: true},
+		{input: `{"query": "hello", "limit":`, want: map[string]any{"query": "hello"}, ok: true},
+		{input: `{"query": "hello", "exact": tr`, want: map[string]any{"query": "hello"}, ok: true},
+		{input: `{"tags": ["a", "b`, want: map[string]any{"tags": []any{"a", "b"}}, ok: true},
+		{input: `{"filter": {"name": "x"`, want: map[string]any{"filter": map[string]any{"name": "x"}}, ok: true},
+		{input: `{"text": "line\`, want: map[string]any{"text": "line"}, ok: true},
+		{input: `{"text": "a\u00`, want: map[string]any{"text": "a"}, ok: true},
+		{input: `{"text": "a, {b"}`, want: map[string]any{"text": "a, {b"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := agent.ParsePartialJSON(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("ParsePartialJSON(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			}
+
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePartialJSON(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArguments(t *testing.T) {
+	call := func(typ agent.StreamChunkType, args string) agent.Chunk {
+		return agent.Chunk{Type: typ, Call: &agent.ToolCall{ID: "call_1", Name: "search", Arguments: args}}
+	}
+
+	acc := agent.NewToolArguments()
+
+	if _, err := acc.Add(call(agent.StreamChunkTypeToolCallStart, "")); err != nil {
+		t.Fatalf("Add(start) failed: %v", err)
+	}
+
+	args, err := acc.Add(call(agent.StreamChunkTypeToolCallDelta, `{"query": "go`))
+	if err != nil {
+		t.Fatalf("Add(delta) failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(args, map[string]any{"query": "go"}) {
+		t.Errorf("partial arguments = %v", args)
+	}
+
+	if _, err := acc.Add(call(agent.StreamChunkTypeToolCallDelta, `lang"}`)); err != nil {
+		t.Fatalf("Add(delta) failed: %v", err)
+	}
+
+	args, err = acc.Add(call(agent.StreamChunkTypeToolCallComplete, ""))
+	if err != nil {
+		t.Fatalf("Add(complete) failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(args, map[string]any{"query": "golang"}) {
+		t.Errorf("complete arguments = %v", args)
+	}
+}
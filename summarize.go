@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Summarize generates a short title of the conversation (e.g. for a chat list), the title is at most maxWords long.
+// It does not require an agent, the request is sent with the given model (a cheap model is usually enough), or with
+// the default model (see SetDefaultModel) if the model is empty.
+func Summarize(ctx context.Context, completer ChatCompleter, model string, msgs []Message, maxWords int) (string, error) {
+	if model == "" {
+		model = defaultModel
+	}
+
+	if model == "" {
+		return "", errors.New("failed to summarize conversation: model is not set, pass a model or use SetDefaultModel")
+	}
+
+	instruction := fmt.Sprintf("Write a concise title for the conversation below, at most %d words. "+
+		"Reply with the title only, without quotes or punctuation at the end.", maxWords)
+
+	resp, err := completer.Complete(ctx, CompletionRequest{
+		Model:    model,
+		Messages: []Message{NewSystemMessage(instruction), NewUserMessage(transcript(msgs))},
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	title := strings.Trim(strings.TrimSpace(AssistantMessage{Content: resp.Content}.Text()), "\"'`.")
+	if title == "" {
+		return "", ErrEmptyResponse
+	}
+
+	if words := strings.Fields(title); len(words) > maxWords && maxWords > 0 {
+		title = strings.Join(words[:maxWords], " ")
+	}
+
+	return title, nil
+}
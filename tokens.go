@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"math"
+	"strings"
+)
+
+// TokenCounter counts tokens a list of messages takes in the model context without making a request to the model.
+type TokenCounter interface {
+	CountMessages(model string, msgs []Message) (int, error)
+}
+
+// ApproximateTokenCounter estimates number of tokens using average number of characters per token.
+type ApproximateTokenCounter struct {
+	CharsPerToken   float64 // average number of characters per token
+	MessageOverhead int     // number of tokens added for each message (role, separators etc)
+}
+
+// NewApproximateTokenCounter creates token counter with a rough estimate of 4 characters per token.
+func NewApproximateTokenCounter() *ApproximateTokenCounter {
+	return &ApproximateTokenCounter{CharsPerToken: 4, MessageOverhead: 4}
+}
+
+func (c *ApproximateTokenCounter) CountMessages(model string, msgs []Message) (int, error) {
+	ratio := c.CharsPerToken
+	if ratio <= 0 {
+		ratio = 4
+	}
+
+	total := 0
+	for _, msg := range msgs {
+		total += c.MessageOverhead + int(math.Ceil(float64(len([]rune(messageText(msg))))/ratio))
+	}
+
+	return total, nil
+}
+
+// messageText returns all textual content of the message as it would be presented to the model.
+func messageText(m Message) string {
+	switch v := m.(type) {
+	case SystemMessage:
+		return v.Content
+	case DeveloperMessage:
+		return v.Content
+	case UserMessage:
+		return v.Content
+	case AssistantMessage:
+		var b strings.Builder
+		for _, block := range v.Content {
+			b.WriteString(block.Text)
+
+			if block.ToolCall != nil {
+				b.WriteString(block.ToolCall.Name)
+				b.WriteString(block.ToolCall.Arguments)
+			}
+
+			if block.ToolResult != nil {
+				b.WriteString(block.ToolResult.String())
+			}
+		}
+
+		return b.String()
+	case ToolResult:
+		return v.String()
+	case ToolError:
+		return v.String()
+	default:
+		return ""
+	}
+}
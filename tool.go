@@ -3,6 +3,9 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/jsonschema-go/jsonschema"
 )
@@ -14,6 +17,8 @@ type Tool struct {
 	InputSchema  *jsonschema.Schema
 	OutputSchema *jsonschema.Schema
 	DeferLoading bool
+	Tags         []string          // tags categorizing the tool (e.g. "read", "write", "destructive"), not sent to the model
+	Metadata     map[string]string // additional information about the tool, not sent to the model
 }
 
 func WithTool(tool Tool, fn func(context.Context, []byte) (any, error)) Option {
@@ -29,3 +34,98 @@ func WithTool(tool Tool, fn func(context.Context, []byte) (any, error)) Option {
 		adder.Add(tool, fn)
 	}
 }
+
+// DelegationOption customizes tools created by WithOrchestratorTool, WithSpecialistTool, WithHandoffTool and
+// WithConsultTool. Use it to rename tools when combining several of them in one agent or to fit them into a domain.
+type DelegationOption func(tool, list *Tool)
+
+// ToolName overrides name of the main tool (e.g. `execute_tasks`, `ask_specialist` or `delegate_to`).
+func ToolName(name string) DelegationOption {
+	return func(tool, _ *Tool) {
+		tool.Name = name
+	}
+}
+
+// ToolDescription overrides description of the main tool.
+func ToolDescription(desc string) DelegationOption {
+	return func(tool, _ *Tool) {
+		tool.Description = desc
+	}
+}
+
+// ListToolName overrides name of the tool listing available agents. Every constructor has its own default, so the
+// tools can be combined in one agent: `list_agents`, `list_specialists`, `list_handoff_specialists` and
+// `list_consultants`.
+func ListToolName(name string) DelegationOption {
+	return func(_, list *Tool) {
+		list.Name = name
+	}
+}
+
+// ListToolDescription overrides description of the tool listing available agents.
+func ListToolDescription(desc string) DelegationOption {
+	return func(_, list *Tool) {
+		list.Description = desc
+	}
+}
+
+// ToolRetry defines how many times a tool is called if it fails with a temporary error, see WithToolRetry.
+type ToolRetry struct {
+	Attempts int           // total number of attempts, including the first one
+	Backoff  time.Duration // delay before the first retry, it's doubled before every next one
+}
+
+// temporary reports if the error is marked as temporary with `Temporary() bool` method.
+func temporary(err error) bool {
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// limitResult truncates tool result or error exceeding the limit, a marker with the number of removed bytes is added.
+func limitResult(m Message, limit int) Message {
+	switch v := m.(type) {
+	case ToolResult:
+		if text := v.String(); len(text) > limit {
+			v.Result = truncate(text, limit)
+		}
+
+		return v
+	case ToolError:
+		v.Error = truncate(v.Error, limit)
+		v.Content = truncate(v.Content, limit)
+		return v
+	default:
+		return m
+	}
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	return fmt.Sprintf("%s...[truncated %d bytes]", text[:cut], len(text)-cut)
+}
+
+// AbortRun wraps an error returned by a tool to stop the agent run, instead of reporting the error to the model. The
+// run returns the error, the original error is available with errors.Is and errors.As.
+func AbortRun(err error) error {
+	return abortError{err: err}
+}
+
+type abortError struct {
+	err error
+}
+
+func (e abortError) Error() string {
+	return e.err.Error()
+}
+
+func (e abortError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+// WithAskUserTool adds `ask_user` tool which lets the agent ask the user a clarifying question instead of guessing.
+// The asker is called with the question and returns the answer of the user, it may block waiting for the answer (e.g.
+// from UI). If the asker fails (e.g. in non-interactive mode), the run is aborted with the error of the asker.
+func WithAskUserTool(asker func(ctx context.Context, question string) (string, error)) Option {
+	type AskRequest struct {
+		Question string `json:"question" jsonschema:"a short clear question to the user"`
+	}
+
+	type AskResponse struct {
+		Answer string `json:"answer"`
+	}
+
+	desc := "Ask the user a clarifying question when the request is ambiguous or required information is missing. " +
+		"Do not use it for questions you can answer yourself."
+
+	return WithInlineTool("ask_user", desc, func(ctx context.Context, in AskRequest) (*AskResponse, error) {
+		if in.Question == "" {
+			return nil, errors.New("question is required")
+		}
+
+		answer, err := asker(ctx, in.Question)
+		if err != nil {
+			return nil, AbortRun(err)
+		}
+
+		return &AskResponse{Answer: answer}, nil
+	})
+}
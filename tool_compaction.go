@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// compactionKeep is the number of the most recent messages kept intact when the context is compacted.
+const compactionKeep = 6
+
+// WithCompactionTool adds `compact_context` tool which lets the agent compress its own context when the conversation
+// grows long. Older messages in memory are summarized by the given completer and model into a single system message,
+// the most recent messages are kept as is. Starter messages of the agent (e.g. system prompt) are not affected.
+//
+// Memory of the agent must implement ReplaceableMemory.
+func WithCompactionTool(completer ChatCompleter, model string) Option {
+	type CompactRequest struct {
+		Focus string `json:"focus,omitempty" jsonschema:"information which must be preserved in the summary, e.g. current task and decisions made so far"`
+	}
+
+	type CompactResult struct {
+		Summarized int `json:"summarized"`
+		Kept       int `json:"kept"`
+	}
+
+	desc := "Compact the conversation context by replacing older messages with a concise summary. " +
+		"Use it when the conversation becomes long and older details are no longer needed verbatim."
+
+	return WithInlineTool("compact_context", desc, func(ctx context.Context, in CompactRequest) (*CompactResult, error) {
+		m, ok := MemoryFromContext(ctx)
+		if !ok {
+			return nil, errors.New("memory is not available")
+		}
+
+		memory, ok := m.(ReplaceableMemory)
+		if !ok {
+			return nil, fmt.Errorf("memory %T does not support compaction", m)
+		}
+
+		messages := memory.List()
+
+		// keep recent messages, but do not start with tool results separated from their tool calls
+		split := max(len(messages)-compactionKeep, 0)
+		for split > 0 && isToolOutput(messages[split]) {
+			split--
+		}
+
+		if split == 0 {
+			return &CompactResult{Kept: len(messages)}, nil
+		}
+
+		instruction := "Summarize the conversation below. Keep facts, decisions, open tasks, and results of tool calls which may be needed later. Be concise."
+		if in.Focus != "" {
+			instruction += " Make sure to preserve: " + in.Focus
+		}
+
+		resp, err := completer.Complete(ctx, CompletionRequest{
+			Model:    model,
+			Messages: []Message{NewSystemMessage(instruction), NewUserMessage(transcript(messages[:split]))},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+		}
+
+		summary := AssistantMessage{Content: resp.Content}.Text()
+
+		compacted := append([]Message{NewSystemMessage("Summary of the earlier conversation:\n\n" + summary)}, messages[split:]...)
+		if err := memory.Replace(ctx, compacted); err != nil {
+			return nil, err
+		}
+
+		return &CompactResult{Summarized: split, Kept: len(messages) - split}, nil
+	})
+}
+
+func isToolOutput(m Message) bool {
+	switch m.(type) {
+	case ToolResult, ToolError:
+		return true
+	default:
+		return false
+	}
+}
+
+// transcript renders messages as plain text, so they can be passed to a model as a part of a prompt.
+func transcript(messages []Message) string {
+	var b strings.Builder
+
+	for _, m := range messages {
+		var role string
+
+		switch m.(type) {
+		case SystemMessage, DeveloperMessage:
+			role = "System"
+		case UserMessage:
+			role = "User"
+		case AssistantMessage:
+			role = "Assistant"
+		case ToolResult, ToolError:
+			role = "Tool"
+		default:
+			continue
+		}
+
+		b.WriteString(role)
+		b.WriteString(": ")
+		b.WriteString(messageText(m))
+		b.WriteString("\n\n")
+	}
+
+	return b.String()
+}
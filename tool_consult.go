@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// WithConsultTool adds `consult_specialist` tool which runs a specialist on the current conversation and returns its
+// answer to the agent, which then continues the conversation (unlike WithHandoffTool, which transfers the conversation
+// to the specialist). The specialist sees the conversation up to the current turn, its own messages are not added to
+// the memory of the agent.
+func WithConsultTool(agents []*Agent, opts ...DelegationOption) Option {
+	type ConsultRequest struct {
+		Specialist string `json:"specialist"`
+		Question   string `json:"question"`
+	}
+
+	type SpecialistDesc struct {
+		Specialist  string `json:"specialist"`
+		Description string `json:"description"`
+	}
+
+	names := make([]any, len(agents))
+	for i, agent := range agents {
+		names[i] = agent.name
+	}
+
+	list := Tool{
+		Name:        "list_consultants",
+		Description: "List specialists available for consultation",
+	}
+
+	consult := Tool{
+		Name:        "consult_specialist",
+		Description: "Ask a specialist for expert advice, the specialist sees the conversation so far and answers the question, then you continue the conversation",
+		InputSchema: SchemaObject(
+			Required("specialist", SchemaString("name of the specialist to consult")),
+			Required("question", SchemaString("the question or the task for the specialist")),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(&consult, &list)
+	}
+
+	return WithOptions(
+		WithTool(list, func(ctx context.Context, in []byte) (any, error) {
+			var items []SpecialistDesc
+			for _, a := range agents {
+				items = append(items, SpecialistDesc{Specialist: a.name, Description: a.description})
+			}
+
+			return items, nil
+		}),
+		WithTool(consult, func(ctx context.Context, in []byte) (any, error) {
+			req := ConsultRequest{}
+			if err := json.Unmarshal(in, &req); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal consult request: %w", err)
+			}
+
+			for _, a := range agents {
+				if a.name != req.Specialist {
+					continue
+				}
+
+				// the conversation so far, without the turn which is calling this tool
+				m := NewStaticMemory()
+				if memory, ok := MemoryFromContext(ctx); ok {
+					messages := memory.List()
+
+					last := len(messages)
+					for last > 0 {
+						last--
+						if _, ok := messages[last].(AssistantMessage); ok {
+							break
+						}
+					}
+
+					for _, message := range messages[:last] {
+						if err := m.Append(ctx, message); err != nil {
+							return nil, err
+						}
+					}
+				}
+
+				if err := m.Append(ctx, NewUserMessage(req.Question)); err != nil {
+					return nil, err
+				}
+
+				reply, err := a.Run(ctx, WithMemory(m))
+				if err != nil {
+					return nil, fmt.Errorf("failed to consult specialist %q: %w", req.Specialist, err)
+				}
+
+				return reply.Text(), nil
+			}
+
+			return nil, fmt.Errorf("specialist %q does not exist, valid values: %v", req.Specialist, names)
+		}),
+	)
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,15 +9,55 @@ import (
 	"github.com/google/jsonschema-go/jsonschema"
 )
 
-func WithInlineTool[In any, Out any](name, desc string, fn func(context.Context, In) (Out, error)) Option {
+// InlineToolOption configures inline tool, see WithInlineTool.
+type InlineToolOption func(*inlineTool)
+
+type inlineTool struct {
+	decode func(data []byte, v any) error
+}
+
+// InlineDecoder replaces the function used to decode tool arguments, by default arguments are decoded with json.Unmarshal.
+func InlineDecoder(decode func(data []byte, v any) error) InlineToolOption {
+	return func(t *inlineTool) {
+		t.decode = decode
+	}
+}
+
+// InlineStrict rejects tool arguments with fields which are not defined in the input type, so mistakes of the model
+// are reported back to it instead of being silently ignored.
+func InlineStrict() InlineToolOption {
+	return InlineDecoder(func(data []byte, v any) error {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(v)
+	})
+}
+
+func WithInlineTool[In any, Out any](name, desc string, fn func(context.Context, In) (Out, error), opts ...InlineToolOption) Option {
+	opt, err := TryInlineTool(name, desc, fn, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return opt
+}
+
+// TryInlineTool works like WithInlineTool, but returns an error instead of panicking if input or output schema can't
+// be generated. Use it when tools are assembled dynamically.
+func TryInlineTool[In any, Out any](name, desc string, fn func(context.Context, In) (Out, error), opts ...InlineToolOption) (Option, error) {
+	cfg := inlineTool{decode: json.Unmarshal}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	is, err := jsonschema.For[In](nil)
 	if err != nil {
-		panic(fmt.Errorf("failed to make input schema for %T: %v", *new(In), err))
+		return nil, fmt.Errorf("failed to make input schema for %T: %v", *new(In), err)
 	}
 
 	os, err := jsonschema.For[Out](nil)
 	if err != nil {
-		panic(fmt.Errorf("failed to make input schema for %T: %v", *new(Out), err))
+		return nil, fmt.Errorf("failed to make output schema for %T: %v", *new(Out), err)
 	}
 
 	tool := Tool{
@@ -28,7 +69,7 @@ func WithInlineTool[In any, Out any](name, desc string, fn func(context.Context,
 
 	return WithTool(tool, func(ctx context.Context, data []byte) (any, error) {
 		var in In
-		if err := json.Unmarshal(data, &in); err != nil {
+		if err := cfg.decode(data, &in); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal tool arguments: %w", err)
 		}
 
@@ -38,5 +79,5 @@ func WithInlineTool[In any, Out any](name, desc string, fn func(context.Context,
 		}
 
 		return out, err
-	})
+	}), nil
 }
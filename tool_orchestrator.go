@@ -5,13 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
-
-	"github.com/google/jsonschema-go/jsonschema"
 )
 
-func WithOrchestratorTool(agents ...*Agent) Option {
+func WithOrchestratorTool(agents []*Agent, opts ...DelegationOption) Option {
 	names := map[string]*Agent{}
 	var desc []string
 
@@ -21,10 +21,12 @@ func WithOrchestratorTool(agents ...*Agent) Option {
 	}
 
 	type Task struct {
-		Agent   string `json:"agent"`
-		Task    string `json:"task"`
-		Outcome string `json:"outcome"`
-		Status  string `json:"status"`
+		ID        string   `json:"id,omitempty"`
+		Agent     string   `json:"agent"`
+		Task      string   `json:"task"`
+		DependsOn []string `json:"depends_on,omitempty"`
+		Outcome   string   `json:"outcome"`
+		Status    string   `json:"status"`
 	}
 
 	type Outcome struct {
@@ -41,42 +43,44 @@ func WithOrchestratorTool(agents ...*Agent) Option {
 		Tasks []Task `json:"tasks"`
 	}
 
+	type AgentDesc struct {
+		Agent       string `json:"agent"`
+		Description string `json:"description"`
+	}
+
+	list := Tool{
+		Name:        "list_agents",
+		Description: "List agents available to perform tasks",
+	}
+
 	planner := Tool{
-		Name:        "execute_tasks",
-		Description: "Execute tasks in the todo list",
-		InputSchema: &jsonschema.Schema{
-			Type:                 "object",
-			AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
-			Required:             []string{"context", "tasks"},
-			Properties: map[string]*jsonschema.Schema{
-				"context": {
-					Type:        "string",
-					Description: "an extended summarization of the conversation so far, context details, requirements, identifiers, names etc",
-				},
-				"tasks": {
-					Type:        "array",
-					Description: "list of tasks to complete",
-					Items: &jsonschema.Schema{
-						Type:                 "object",
-						AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
-						Required:             []string{"agent", "task"},
-						Properties: map[string]*jsonschema.Schema{
-							"agent": {
-								Type:        "string",
-								Description: "name of the agent in charge of performing this task, available agents are:\n" + strings.Join(desc, "\n"),
-							},
-							"task": {
-								Type:        "string",
-								Description: "detailed explanation of the task for the agent, what she has to do and what outcome is expected",
-							},
-						},
-					},
-				},
-			},
-		},
+		Name: "execute_tasks",
+		Description: "Execute tasks in the todo list. Tasks may depend on other tasks, a task starts once all its dependencies are complete " +
+			"and receives their outcomes. Independent tasks run concurrently. If no task declares dependencies, tasks are executed one after another.",
+		InputSchema: SchemaObject(
+			Required("context", SchemaString("an extended summarization of the conversation so far, context details, requirements, identifiers, names etc")),
+			Required("tasks", SchemaArray("list of tasks to complete", SchemaObject(
+				Optional("id", SchemaString("unique identifier of the task, used to reference it in depends_on of other tasks")),
+				Required("agent", SchemaString("name of the agent in charge of performing this task, available agents are:\n"+strings.Join(desc, "\n"))),
+				Required("task", SchemaString("detailed explanation of the task for the agent, what she has to do and what outcome is expected")),
+				Optional("depends_on", SchemaArray("identifiers of the tasks which must be complete before this task starts", SchemaString("task identifier"))),
+			))),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(&planner, &list)
 	}
 
 	return WithOptions(
+		WithTool(list, func(ctx context.Context, in []byte) (any, error) {
+			var items []AgentDesc
+			for _, a := range agents {
+				items = append(items, AgentDesc{Agent: a.name, Description: a.description})
+			}
+
+			return items, nil
+		}),
 		WithTool(planner, func(ctx context.Context, in []byte) (any, error) {
 			req := OrchestrationRequest{}
 			if err := json.Unmarshal(in, &req); err != nil {
@@ -91,51 +95,200 @@ func WithOrchestratorTool(agents ...*Agent) Option {
 				}
 			}
 
+			ids := make([]string, len(todo))
+			deps := make([][]string, len(todo))
 			for idx, task := range todo {
-				idx := idx
-
-				agent, ok := names[task.Agent]
-				if !ok {
-					continue
-				}
-
-				m := NewStaticMemory()
-
-				if err := m.Append(ctx, NewAssistantMessage(req.Context)); err != nil {
-					return nil, err
-				}
+				ids[idx], deps[idx] = task.ID, task.DependsOn
+			}
 
-				if err := m.Append(ctx, NewUserMessage("You have to perform the task described below and call `complete_task` to communicate the results. \n\nThe task: "+task.Task)); err != nil {
-					return nil, err
-				}
+			planTasks(ids, deps)
 
-				complete := func(s, r string) {
-					todo[idx].Status = s
-					todo[idx].Outcome = r
-				}
+			for idx := range todo {
+				todo[idx].ID, todo[idx].DependsOn = ids[idx], deps[idx]
+			}
 
-				if _, err := agent.Run(ctx, WithMemory(m), withCompletionTool(complete)); err != nil {
-					todo[idx].Status = "FAILED"
-					todo[idx].Outcome = "ERROR: " + err.Error()
-					break
-				}
+			index, err := taskGraph(len(todo), func(i int) (string, []string) { return todo[i].ID, todo[i].DependsOn })
+			if err != nil {
+				return nil, err
+			}
 
-				if todo[idx].Status == "" {
-					todo[idx].Status = "FAILED"
-					todo[idx].Outcome = "ERROR: agent did not respond"
-					break
-				}
+			done := make([]chan struct{}, len(todo))
+			for idx := range done {
+				done[idx] = make(chan struct{})
+			}
 
-				if todo[idx].Status == "FAILED" {
-					break
-				}
+			wg := sync.WaitGroup{}
+			for idx := range todo {
+				wg.Add(1)
+				go func(idx int) {
+					defer wg.Done()
+					defer close(done[idx])
+
+					task := todo[idx]
+
+					// wait for dependencies and collect their outcomes
+					var outcomes []string
+					for _, dep := range task.DependsOn {
+						d := index[dep]
+
+						select {
+						case <-done[d]:
+						case <-ctx.Done():
+							todo[idx].Status = "FAILED"
+							todo[idx].Outcome = "ERROR: " + ctx.Err().Error()
+							return
+						}
+
+						if todo[d].Status != "COMPLETE" {
+							todo[idx].Status = "SKIPPED"
+							todo[idx].Outcome = fmt.Sprintf("task %q it depends on is not complete", dep)
+							return
+						}
+
+						outcomes = append(outcomes, fmt.Sprintf("  - `%s` (%s): %s", todo[d].ID, todo[d].Task, todo[d].Outcome))
+					}
+
+					agent := names[task.Agent]
+
+					prompt := "You have to perform the task described below and call `complete_task` to communicate the results. \n\nThe task: " + task.Task
+					if len(outcomes) > 0 {
+						prompt += "\n\nOutcomes of the tasks it depends on:\n" + strings.Join(outcomes, "\n")
+					}
+
+					m := NewStaticMemory()
+
+					if err := m.Append(ctx, NewAssistantMessage(req.Context)); err != nil {
+						todo[idx].Status = "FAILED"
+						todo[idx].Outcome = "ERROR: " + err.Error()
+						return
+					}
+
+					if err := m.Append(ctx, NewUserMessage(prompt)); err != nil {
+						todo[idx].Status = "FAILED"
+						todo[idx].Outcome = "ERROR: " + err.Error()
+						return
+					}
+
+					complete := func(s, r string) {
+						todo[idx].Status = s
+						todo[idx].Outcome = r
+					}
+
+					if _, err := agent.Run(ctx, WithMemory(m), withCompletionTool(complete)); err != nil {
+						todo[idx].Status = "FAILED"
+						todo[idx].Outcome = "ERROR: " + err.Error()
+						return
+					}
+
+					if todo[idx].Status == "" {
+						todo[idx].Status = "FAILED"
+						todo[idx].Outcome = "ERROR: agent did not respond"
+					}
+				}(idx)
 			}
 
+			wg.Wait()
+
 			return &OrchestrationResponse{Tasks: todo}, nil
 		}),
 	)
 }
 
+// planTasks assigns identifiers to tasks which have none. If no task declares dependencies, tasks run one after
+// another, like a plain todo list, so each task is made dependent on the previous one.
+func planTasks(ids []string, deps [][]string) {
+	taken := map[string]bool{}
+	for _, id := range ids {
+		taken[id] = true
+	}
+
+	sequential := true
+	for idx := range ids {
+		if ids[idx] == "" {
+			ids[idx] = taskID(idx, taken)
+		}
+
+		if len(deps[idx]) > 0 {
+			sequential = false
+		}
+	}
+
+	if sequential {
+		for idx := 1; idx < len(ids); idx++ {
+			deps[idx] = []string{ids[idx-1]}
+		}
+	}
+}
+
+// taskID returns identifier for the task at the position, which is not taken by other tasks, and marks it as taken.
+// Generated identifiers are prefixed, so they are unlikely to collide with identifiers given by the model.
+func taskID(idx int, taken map[string]bool) string {
+	id := "task_" + strconv.Itoa(idx+1)
+	for n := 2; taken[id]; n++ {
+		id = "task_" + strconv.Itoa(idx+1) + "_" + strconv.Itoa(n)
+	}
+
+	taken[id] = true
+	return id
+}
+
+// taskGraph validates dependencies between n tasks and returns position of each task by its identifier. It's an
+// error if identifiers are not unique, a dependency does not exist or dependencies form a cycle.
+func taskGraph(n int, task func(i int) (id string, deps []string)) (map[string]int, error) {
+	index := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		id, _ := task(i)
+		if _, ok := index[id]; ok {
+			return nil, fmt.Errorf("task id %q is not unique", id)
+		}
+
+		index[id] = i
+	}
+
+	for i := 0; i < n; i++ {
+		id, deps := task(i)
+		for _, dep := range deps {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("task %q depends on task %q which does not exist", id, dep)
+			}
+		}
+	}
+
+	// depth-first search: 1 - task is being visited, 2 - task and its dependencies are visited
+	state := make([]int, n)
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			id, _ := task(i)
+			return fmt.Errorf("dependencies of task %q form a cycle", id)
+		case 2:
+			return nil
+		}
+
+		state[i] = 1
+
+		_, deps := task(i)
+		for _, dep := range deps {
+			if err := visit(index[dep]); err != nil {
+				return err
+			}
+		}
+
+		state[i] = 2
+		return nil
+	}
+
+	for i := 0; i < n; i++ {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
 func withCompletionTool(f func(status string, reasoning string)) Option {
 	type CompletionRequest struct {
 		Status    string `json:"status"`
@@ -145,21 +298,10 @@ func withCompletionTool(f func(status string, reasoning string)) Option {
 	completer := Tool{
 		Name:        "complete_task",
 		Description: "Mark task as completed or to report an failure",
-		InputSchema: &jsonschema.Schema{
-			Type:                 "object",
-			AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
-			Required:             []string{"status", "reasoning"},
-			Properties: map[string]*jsonschema.Schema{
-				"status": {
-					Type:        "string",
-					Description: "COMPLETE if task is successfully complete; FAILURE if task is incomplete",
-				},
-				"reasoning": {
-					Type:        "string",
-					Description: "the summary of what actions have been taken and their outcome",
-				},
-			},
-		},
+		InputSchema: SchemaObject(
+			Required("status", SchemaString("COMPLETE if task is successfully complete; FAILURE if task is incomplete")),
+			Required("reasoning", SchemaString("the summary of what actions have been taken and their outcome")),
+		),
 	}
 
 	acked := atomic.Bool{}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTaskGraph(t *testing.T) {
+	type task struct {
+		id   string
+		deps []string
+	}
+
+	tests := map[string]struct {
+		tasks []task
+		want  map[string]int
+		err   string
+	}{
+		"no dependencies": {
+			tasks: []task{{id: "a"}, {id: "b"}},
+			want:  map[string]int{"a": 0, "b": 1},
+		},
+		"diamond": {
+			tasks: []task{{id: "a"}, {id: "b", deps: []string{"a"}}, {id: "c", deps: []string{"a"}}, {id: "d", deps: []string{"b", "c"}}},
+			want:  map[string]int{"a": 0, "b": 1, "c": 2, "d": 3},
+		},
+		"cycle": {
+			tasks: []task{{id: "a", deps: []string{"c"}}, {id: "b", deps: []string{"a"}}, {id: "c", deps: []string{"b"}}},
+			err:   "form a cycle",
+		},
+		"self dependency": {
+			tasks: []task{{id: "a", deps: []string{"a"}}},
+			err:   "form a cycle",
+		},
+		"unknown dependency": {
+			tasks: []task{{id: "a"}, {id: "b", deps: []string{"x"}}},
+			err:   `task "b" depends on task "x" which does not exist`,
+		},
+		"duplicate id": {
+			tasks: []task{{id: "a"}, {id: "a"}},
+			err:   `task id "a" is not unique`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := taskGraph(len(tc.tasks), func(i int) (string, []string) { return tc.tasks[i].id, tc.tasks[i].deps })
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("taskGraph() error = %v, want %q", err, tc.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("taskGraph() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("taskGraph() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanTasks(t *testing.T) {
+	t.Run("chains tasks without dependencies", func(t *testing.T) {
+		ids := []string{"", "b", ""}
+		deps := make([][]string, 3)
+
+		planTasks(ids, deps)
+
+		if want := []string{"task_1", "b", "task_3"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("ids = %v, want %v", ids, want)
+		}
+
+		if want := [][]string{nil, {"task_1"}, {"b"}}; !reflect.DeepEqual(deps, want) {
+			t.Errorf("deps = %v, want %v", deps, want)
+		}
+	})
+
+	t.Run("keeps declared dependencies", func(t *testing.T) {
+		ids := []string{"a", "b", "c"}
+		deps := [][]string{nil, nil, {"a"}}
+
+		planTasks(ids, deps)
+
+		if want := [][]string{nil, nil, {"a"}}; !reflect.DeepEqual(deps, want) {
+			t.Errorf("deps = %v, want %v", deps, want)
+		}
+	})
+
+	t.Run("generated ids do not collide with given ones", func(t *testing.T) {
+		ids := []string{"", "task_1", ""}
+		deps := make([][]string, 3)
+
+		planTasks(ids, deps)
+
+		if want := []string{"task_1_2", "task_1", "task_3"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("ids = %v, want %v", ids, want)
+		}
+
+		if _, err := taskGraph(len(ids), func(i int) (string, []string) { return ids[i], deps[i] }); err != nil {
+			t.Errorf("taskGraph() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WithRetrievalTool adds `search_knowledge` tool which embeds the query and returns the most similar documents from the store.
+func WithRetrievalTool(store VectorStore, embedder Embedder) Option {
+	type SearchRequest struct {
+		Query string `json:"query" jsonschema:"search query describing the information you are looking for"`
+		Limit int    `json:"limit,omitempty" jsonschema:"maximum number of documents to return, defaults to 5"`
+	}
+
+	type SearchResult struct {
+		Content  string            `json:"content"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+		Score    float64           `json:"score"`
+	}
+
+	return WithInlineTool("search_knowledge", "Search the knowledge base for documents relevant to the query", func(ctx context.Context, in SearchRequest) ([]SearchResult, error) {
+		if in.Query == "" {
+			return nil, errors.New("query is required")
+		}
+
+		limit := in.Limit
+		if limit <= 0 {
+			limit = 5
+		}
+
+		vectors, err := embedder.Embed(ctx, in.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+
+		if len(vectors) == 0 {
+			return nil, errors.New("embedder returned no vectors")
+		}
+
+		docs, err := store.Query(ctx, vectors[0], limit)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]SearchResult, len(docs))
+		for i, doc := range docs {
+			results[i] = SearchResult{Content: doc.Content, Metadata: doc.Metadata, Score: doc.Score}
+		}
+
+		return results, nil
+	})
+}
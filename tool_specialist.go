@@ -4,11 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-
-	"github.com/google/jsonschema-go/jsonschema"
 )
 
-func WithSpecialistTool(agents ...*Agent) Option {
+func WithSpecialistTool(agents []*Agent, opts ...DelegationOption) Option {
 	type SpecialistRequest struct {
 		Specialist string `json:"specialist"`
 		Task       string `json:"task"`
@@ -33,29 +31,19 @@ func WithSpecialistTool(agents ...*Agent) Option {
 	delegate := Tool{
 		Name:        "ask_specialist",
 		Description: "Handoff conversation to a specialist who can provide expert-level assistance for the user",
-		InputSchema: &jsonschema.Schema{
-			Type:                 "object",
-			AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
-			Required:             []string{"specialist", "task", "context"},
-			Properties: map[string]*jsonschema.Schema{
-				"specialist": {
-					Type:        "string",
-					Description: "name of the specialist in charge of handling the conversation",
-				},
-				"task": {
-					Type:        "string",
-					Description: "detailed explanation of the task for the specialist, what she has to do",
-				},
-				"context": {
-					Type:        "string",
-					Description: "an extended summarization of the conversation so far",
-				},
-			},
-		},
+		InputSchema: SchemaObject(
+			Required("specialist", SchemaString("name of the specialist in charge of handling the conversation")),
+			Required("task", SchemaString("detailed explanation of the task for the specialist, what she has to do")),
+			Required("context", SchemaString("an extended summarization of the conversation so far")),
+		),
+	}
+
+	for _, opt := range opts {
+		opt(&delegate, &list)
 	}
 
 	return func(agent *Agent) {
-		opts := []Option{
+		tools := []Option{
 			WithTool(list, func(ctx context.Context, in []byte) (any, error) {
 				var items []SpecialistDesc
 				for _, a := range agents {
@@ -104,8 +92,8 @@ func WithSpecialistTool(agents ...*Agent) Option {
 			}),
 		}
 
-		for _, opt := range opts {
-			opt(agent)
+		for _, tool := range tools {
+			tool(agent)
 		}
 	}
 }
@@ -2,7 +2,7 @@ package agent
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 )
 
 type Toolset interface {
@@ -10,6 +10,24 @@ type Toolset interface {
 	List() []Tool
 }
 
+// LookupTool returns the tool with the given name from the toolset, it uses Get method of the toolset if available
+// and falls back to scanning the list of tools otherwise.
+func LookupTool(toolset Toolset, name string) (Tool, bool) {
+	if getter, ok := toolset.(interface {
+		Get(name string) (Tool, bool)
+	}); ok {
+		return getter.Get(name)
+	}
+
+	for _, tool := range toolset.List() {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+
+	return Tool{}, false
+}
+
 type ToolHandlerFunc func(context.Context, []byte) (any, error)
 
 type StaticToolset struct {
@@ -24,7 +42,7 @@ func NewStaticToolset() *StaticToolset {
 func (t *StaticToolset) Call(ctx context.Context, function string, args []byte) (any, error) {
 	h, ok := t.handlers[function]
 	if !ok {
-		return nil, fmt.Errorf("unknown tool %q", function)
+		return nil, NewUnknownToolError(function, t.tools)
 	}
 
 	return h(ctx, args)
@@ -34,7 +52,49 @@ func (t *StaticToolset) List() []Tool {
 	return t.tools
 }
 
+// Get returns the tool registered with the given name.
+func (t *StaticToolset) Get(name string) (Tool, bool) {
+	if _, ok := t.handlers[name]; !ok {
+		return Tool{}, false
+	}
+
+	for _, tool := range t.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+
+	return Tool{}, false
+}
+
+// Add registers a tool, a tool with the same name registered earlier is replaced.
 func (t *StaticToolset) Add(tool Tool, handler ToolHandlerFunc) {
-	t.tools = append(t.tools, tool)
+	if _, ok := t.handlers[tool.Name]; ok {
+		slog.Warn("Tool is registered more than once, previous definition is replaced", "channel", "llm", "tool", tool.Name)
+
+		for i := range t.tools {
+			if t.tools[i].Name == tool.Name {
+				t.tools[i] = tool
+			}
+		}
+	} else {
+		t.tools = append(t.tools, tool)
+	}
+
 	t.handlers[tool.Name] = handler
 }
+
+// clone creates a copy of the toolset, so tools added to the copy are not visible in the original.
+func (t *StaticToolset) clone() *StaticToolset {
+	c := &StaticToolset{
+		tools:    make([]Tool, len(t.tools)),
+		handlers: make(map[string]ToolHandlerFunc, len(t.handlers)),
+	}
+
+	copy(c.tools, t.tools)
+	for k, v := range t.handlers {
+		c.handlers[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+)
+
+// MergedToolset combines tools of several toolsets (e.g. tools of an external server with hand-written tools), calls
+// are routed to the toolset which lists the tool. If several toolsets list a tool with the same name, the first one
+// wins, such names are detected when the toolset is created and reported by Collisions. Tools added to the merged
+// toolset (e.g. with WithInlineTool) take precedence over tools of the toolsets.
+type MergedToolset struct {
+	local      *StaticToolset
+	toolsets   []Toolset
+	collisions []string
+}
+
+func NewMergedToolset(toolsets ...Toolset) *MergedToolset {
+	var collisions []string
+	seen := map[string]bool{}
+
+	for _, ts := range toolsets {
+		for _, tool := range ts.List() {
+			if seen[tool.Name] {
+				collisions = append(collisions, tool.Name)
+				continue
+			}
+
+			seen[tool.Name] = true
+		}
+	}
+
+	if len(collisions) > 0 {
+		slog.Warn("Tools are provided by more than one toolset, only the first one is used", "channel", "llm", "tools", collisions)
+	}
+
+	return &MergedToolset{local: NewStaticToolset(), toolsets: toolsets, collisions: collisions}
+}
+
+// Collisions returns names of tools provided by more than one toolset at the time the merged toolset was created.
+func (t *MergedToolset) Collisions() []string {
+	return append([]string(nil), t.collisions...)
+}
+
+func (t *MergedToolset) Call(ctx context.Context, function string, args []byte) (any, error) {
+	for _, ts := range t.all() {
+		if _, ok := LookupTool(ts, function); ok {
+			return ts.Call(ctx, function, args)
+		}
+	}
+
+	return nil, NewUnknownToolError(function, t.List())
+}
+
+func (t *MergedToolset) List() []Tool {
+	var tools []Tool
+	seen := map[string]bool{}
+
+	for _, ts := range t.all() {
+		for _, tool := range ts.List() {
+			if seen[tool.Name] {
+				continue
+			}
+
+			seen[tool.Name] = true
+			tools = append(tools, tool)
+		}
+	}
+
+	return tools
+}
+
+func (t *MergedToolset) Get(name string) (Tool, bool) {
+	for _, ts := range t.all() {
+		if tool, ok := LookupTool(ts, name); ok {
+			return tool, true
+		}
+	}
+
+	return Tool{}, false
+}
+
+// Add registers a tool in the merged toolset itself.
+func (t *MergedToolset) Add(tool Tool, handler ToolHandlerFunc) {
+	t.local.Add(tool, handler)
+}
+
+func (t *MergedToolset) all() []Toolset {
+	return append([]Toolset{t.local}, t.toolsets...)
+}
+
+// clone creates a copy of the toolset, so tools added to the copy are not visible in the original.
+func (t *MergedToolset) clone() *MergedToolset {
+	return &MergedToolset{local: t.local.clone(), toolsets: append([]Toolset(nil), t.toolsets...), collisions: t.collisions}
+}
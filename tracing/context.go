@@ -9,6 +9,9 @@ type contextKey int
 const (
 	contextSpan contextKey = iota
 	contextRoot
+	contextTags
+	contextBaggage
+	contextProject
 )
 
 func SpanFromContext(ctx context.Context) (Span, bool) {
@@ -20,3 +23,60 @@ func RootFromContext(ctx context.Context) (Span, bool) {
 	s, ok := ctx.Value(contextRoot).(Span)
 	return s, ok
 }
+
+// WithTags returns context which attaches given tags to every span started under it (in addition to tags already
+// attached by the parent context).
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	if len(tags) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextTags, append(TagsFromContext(ctx), tags...))
+}
+
+func TagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(contextTags).([]string)
+	return append([]string(nil), tags...)
+}
+
+// WithBaggage returns context which attaches given values as metadata to every span started under it. Use it to
+// correlate traces with application entities, such as user or session ID. Values are merged with the baggage of the
+// parent context, new values take precedence.
+func WithBaggage(ctx context.Context, values map[string]any) context.Context {
+	if len(values) == 0 {
+		return ctx
+	}
+
+	baggage := BaggageFromContext(ctx)
+	for k, v := range values {
+		baggage[k] = v
+	}
+
+	return context.WithValue(ctx, contextBaggage, baggage)
+}
+
+func BaggageFromContext(ctx context.Context) map[string]any {
+	values, _ := ctx.Value(contextBaggage).(map[string]any)
+
+	baggage := make(map[string]any, len(values))
+	for k, v := range values {
+		baggage[k] = v
+	}
+
+	return baggage
+}
+
+// WithProject returns context which sends spans started under it to the given project instead of the project of the
+// tracer, so a single tracer can serve several products.
+func WithProject(ctx context.Context, project string) context.Context {
+	if project == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextProject, project)
+}
+
+func ProjectFromContext(ctx context.Context) (string, bool) {
+	project, ok := ctx.Value(contextProject).(string)
+	return project, ok
+}
@@ -7,6 +7,7 @@ import (
 
 type Span struct {
 	tracer   *Tracer
+	project  string // project the span is sent to, if empty the project of the tracer is used
 	id       string
 	root     string // root span id
 	parent   string // parent span id
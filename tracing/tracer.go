@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -22,16 +23,25 @@ type Tracer struct {
 	opts    []SpanOption
 	wg      sync.WaitGroup
 	stream  chan Span
+	ctx     context.Context // context of upload requests, it's cancelled when Close gives up waiting
+	cancel  context.CancelFunc
 }
 
 func NewTracer(cli braintrust.Client, project string, opts ...SpanOption) *Tracer {
-	t := &Tracer{cli: cli, project: project, opts: opts, stream: make(chan Span, SpanBufferSize)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &Tracer{cli: cli, project: project, opts: opts, stream: make(chan Span, SpanBufferSize), ctx: ctx, cancel: cancel}
 	t.run()
 
 	return t
 }
 
 func (t *Tracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (Span, context.Context) {
+	// spans are not recorded if project is not configured, do not waste time building them
+	if t.project == "" {
+		return Span{}, ctx
+	}
+
 	sid := uuid.New().String()
 
 	span := Span{
@@ -50,6 +60,13 @@ func (t *Tracer) StartSpan(ctx context.Context, name string, opts ...SpanOption)
 		span.parent = parent.id
 	}
 
+	span.tags = TagsFromContext(ctx)
+	span.project, _ = ProjectFromContext(ctx)
+
+	for k, v := range BaggageFromContext(ctx) {
+		span.SetMetadata(k, v)
+	}
+
 	for _, opt := range append(t.opts, opts...) {
 		opt(&span)
 	}
@@ -124,13 +141,35 @@ func (t *Tracer) send(spans []Span) error {
 		positions[span.id] = index
 	}
 
-	req := braintrust.ProjectLogInsertParams{}
+	// group the latest versions of spans by project
+	projects := map[string][]Span{}
 	for index, span := range spans {
-		// skip older versions of the span
 		if p, ok := positions[span.id]; ok && p > index {
 			continue
 		}
 
+		project := span.project
+		if project == "" {
+			project = t.project
+		}
+
+		projects[project] = append(projects[project], span)
+	}
+
+	var errs []error
+	for project, batch := range projects {
+		if err := t.insert(project, batch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *Tracer) insert(project string, spans []Span) error {
+	req := braintrust.ProjectLogInsertParams{}
+	for _, span := range spans {
+
 		event := shared.InsertProjectLogsEventParam{
 			ID:         param.NewOpt(span.id),
 			Created:    param.NewOpt(span.start),
@@ -167,38 +206,43 @@ func (t *Tracer) send(spans []Span) error {
 			event.Metrics.End = param.NewOpt(float64(span.end.UnixMilli()) / 1000.0)
 		}
 
+		// known keys are moved to dedicated fields, the span maps are left intact because a failed batch is sent again
 		if m := span.metrics; m != nil {
-			if v, ok := m["completion_tokens"]; ok {
-				event.Metrics.CompletionTokens = param.NewOpt(int64(v))
-				delete(m, "completion_tokens")
-			}
-
-			if v, ok := m["prompt_tokens"]; ok {
-				event.Metrics.PromptTokens = param.NewOpt(int64(v))
-				delete(m, "prompt_tokens")
-			}
-
-			if v, ok := m["tokens"]; ok {
-				event.Metrics.Tokens = param.NewOpt(int64(v))
-				delete(m, "tokens")
+			extra := map[string]float64{}
+			for k, v := range m {
+				switch k {
+				case "completion_tokens":
+					event.Metrics.CompletionTokens = param.NewOpt(int64(v))
+				case "prompt_tokens":
+					event.Metrics.PromptTokens = param.NewOpt(int64(v))
+				case "tokens":
+					event.Metrics.Tokens = param.NewOpt(int64(v))
+				default:
+					extra[k] = v
+				}
 			}
 
-			event.Metrics.ExtraFields = m
+			event.Metrics.ExtraFields = extra
 		}
 
 		if m := span.metadata; m != nil {
-			if v, ok := m["model"]; ok {
-				event.Metadata.Model = param.NewOpt(fmt.Sprint(v))
-				delete(m, "model")
+			extra := map[string]any{}
+			for k, v := range m {
+				if k == "model" {
+					event.Metadata.Model = param.NewOpt(fmt.Sprint(v))
+					continue
+				}
+
+				extra[k] = v
 			}
 
-			event.Metadata.ExtraFields = m
+			event.Metadata.ExtraFields = extra
 		}
 
 		req.Events = append(req.Events, event)
 	}
 
-	_, err := t.cli.Projects.Logs.Insert(context.Background(), t.project, req)
+	_, err := t.cli.Projects.Logs.Insert(t.ctx, project, req)
 	return err
 }
 
@@ -208,6 +252,12 @@ func (t *Tracer) record(span Span) {
 		return
 	}
 
+	// the span keeps being modified by its owner, the recorded copy must not share maps and slices with it
+	span.tags = append([]string(nil), span.tags...)
+	span.metrics = copyMap(span.metrics)
+	span.metadata = copyMap(span.metadata)
+	span.context = copyMap(span.context)
+
 	// try to record, but if buffer is overflowing, just discard it
 	select {
 	case t.stream <- span:
@@ -215,7 +265,41 @@ func (t *Tracer) record(span Span) {
 	}
 }
 
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
+
+// Close flushes recorded spans and stops the tracer, it blocks until spans are uploaded.
 func (t *Tracer) Close() {
+	_ = t.CloseContext(context.Background())
+}
+
+// CloseContext flushes recorded spans and stops the tracer. If the context expires before spans are uploaded, the
+// upload is cancelled, remaining spans are abandoned and the context error is returned.
+func (t *Tracer) CloseContext(ctx context.Context) error {
 	close(t.stream)
-	t.wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.cancel()
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,87 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/braintrustdata/braintrust-go"
+	"github.com/braintrustdata/braintrust-go/option"
+	"github.com/eolymp/go-agent/tracing"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run("span is modified while being uploaded", func(t *testing.T) {
+		var mu sync.Mutex
+		var events []map[string]any
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Events []map[string]any `json:"events"`
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Unable to decode request: %v", err)
+			}
+
+			mu.Lock()
+			events = append(events, body.Events...)
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"row_ids":[]}`))
+		}))
+		defer srv.Close()
+
+		tracer := tracing.NewTracer(braintrust.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("test")), "project")
+
+		ctx := tracing.WithBaggage(context.Background(), map[string]any{"user_id": "u1"})
+
+		span, _ := tracer.StartSpan(ctx, "completion", tracing.Attr("model", "gpt-4o"), tracing.Metric("tokens", 10))
+		span.Close()
+
+		done := make(chan struct{})
+		go func() {
+			tracer.Close()
+			close(done)
+		}()
+
+		// keep modifying the span while the tracer uploads it
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+			default:
+				span.SetMetadata("run_id", i)
+				span.SetMetric("step", float64(i))
+				span.SetTag("tag")
+				continue
+			}
+
+			break
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(events) != 1 {
+			t.Fatalf("Expected 1 uploaded event, got %d", len(events))
+		}
+
+		metadata, _ := events[0]["metadata"].(map[string]any)
+		if metadata["model"] != "gpt-4o" {
+			t.Errorf("Expected model %q in metadata, got %v", "gpt-4o", metadata["model"])
+		}
+
+		if metadata["user_id"] != "u1" {
+			t.Errorf("Expected baggage %q in metadata, got %v", "u1", metadata["user_id"])
+		}
+
+		metrics, _ := events[0]["metrics"].(map[string]any)
+		if metrics["tokens"] != float64(10) {
+			t.Errorf("Expected 10 tokens in metrics, got %v", metrics["tokens"])
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Embedder converts texts into embedding vectors.
+// This abstraction allows for different embedding providers (OpenAI, Voyage, local models, etc.)
+type Embedder interface {
+	Embed(ctx context.Context, texts ...string) ([][]float64, error)
+}
+
+// Document is a piece of knowledge stored in a vector store.
+type Document struct {
+	ID       string            `json:"id"`
+	Content  string            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float64         `json:"-"`
+	Score    float64           `json:"score,omitempty"` // similarity score, populated by Query
+}
+
+// VectorStore provides an interface to store and search documents by their embedding vectors.
+type VectorStore interface {
+	Upsert(ctx context.Context, docs ...Document) error
+	Query(ctx context.Context, vector []float64, limit int) ([]Document, error)
+}
+
+// InMemoryVectorStore keeps documents in-memory and searches them using cosine similarity.
+type InMemoryVectorStore struct {
+	lock sync.Mutex
+	docs map[string]Document
+}
+
+func NewInMemoryVectorStore() *InMemoryVectorStore {
+	return &InMemoryVectorStore{docs: make(map[string]Document)}
+}
+
+func (s *InMemoryVectorStore) Upsert(ctx context.Context, docs ...Document) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, doc := range docs {
+		s.docs[doc.ID] = doc
+	}
+
+	return nil
+}
+
+func (s *InMemoryVectorStore) Query(ctx context.Context, vector []float64, limit int) ([]Document, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make([]Document, 0, len(s.docs))
+	for _, doc := range s.docs {
+		doc.Score = cosine(vector, doc.Vector)
+		result = append(result, doc)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score == result[j].Score {
+			return result[i].ID < result[j].ID
+		}
+
+		return result[i].Score > result[j].Score
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// cosine calculates cosine similarity between two vectors, vectors of different length are not similar at all.
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}